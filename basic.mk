@@ -18,7 +18,8 @@ endif
 ifeq ($(GITCOMMIT),)
     GITCOMMIT := ${GITHUB_SHA}
 endif
-CTIMEVAR=-X $(PKG)/version.GITCOMMIT=$(GITCOMMIT) -X $(PKG)/version.VERSION=$(VERSION)
+BUILDDATE := $(shell date -u +%Y-%m-%dT%H:%M:%SZ)
+CTIMEVAR=-X $(PKG)/version.GITCOMMIT=$(GITCOMMIT) -X $(PKG)/version.VERSION=$(VERSION) -X $(PKG)/version.BUILDDATE=$(BUILDDATE)
 GO_LDFLAGS=-ldflags "-w $(CTIMEVAR)"
 GO_LDFLAGS_STATIC=-ldflags "-w $(CTIMEVAR) -extldflags -static"
 
@@ -43,7 +44,7 @@ endif
 .PHONY: build
 build: prebuild $(NAME) ## Builds a dynamic executable or package.
 
-$(NAME): $(wildcard *.go) $(wildcard */*.go) VERSION.txt
+$(NAME): $(wildcard *.go) $(wildcard */*.go) $(wildcard */*/*.go) VERSION.txt
 	@echo "+ $@"
 	$(GO) build -tags "$(BUILDTAGS)" ${GO_LDFLAGS} -o $(NAME) .
 
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// BoardView is a board as shown by the board command, with the titles of
+// its issues.
+type BoardView struct {
+	Pipelines []PipelineView `json:"pipelines"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// PipelineView is a pipeline as shown by the board command.
+type PipelineView struct {
+	ID     string      `json:"id"`
+	Name   string      `json:"name"`
+	Issues []IssueView `json:"issues"`
+}
+
+// IssueView is an issue as shown by the board command. Title is empty if it
+// couldn't be looked up on GitHub.
+type IssueView struct {
+	IssueNumber int    `json:"issue_number"`
+	Title       string `json:"title"`
+	Estimate    *int   `json:"estimate"`
+}
+
+// GetBoard fetches the board of the given workspace and repository,
+// re-fetching a truncated board if the `retry-on-truncation` flag is set. If
+// ZenHub doesn't find the board, the workspace ID is removed from the cache
+// of workspace names in case it is stale.
+func GetBoard(ctx *cli.Context, client *zenhub.Client, workspaceID string, repositoryID uint) (*zenhub.Board, error) {
+	board, err := client.GetBoard(workspaceID, repositoryID, ctx.Bool("retry-on-truncation"))
+	if zenhub.HasStatusCode(err, 404) {
+		InvalidateWorkspaceID(workspaceID)
+	}
+	return board, err
+}
+
+// ShowBoardCommand prints each pipeline on the board, in board order, with
+// the number, title and estimate of the issues in it. The `pipeline` flag
+// limits the output to a single pipeline, given by ID or name.
+//
+// ZenHub doesn't know issue titles so they are looked up on GitHub, one
+// request per issue, unless `no-titles` is set.
+func ShowBoardCommand(ctx *cli.Context) error {
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	pipelines := board.Pipelines
+	if filter := ctx.String("pipeline"); filter != "" {
+		index := zenhub.NewBoardIndex(board)
+		pipelineID, err := index.MatchPipelineID(filter)
+		if err != nil {
+			return err
+		}
+		pipelines = []zenhub.Pipeline{*index.Pipeline(pipelineID)}
+	}
+
+	titles := newIssueTitleLookup(ctx, repositoryID, !ctx.Bool("no-titles"))
+	view := BoardView{
+		Pipelines: make([]PipelineView, 0, len(pipelines)),
+		Resolved: ResolvedIDs{
+			WorkspaceID:  workspaceID,
+			RepositoryID: repositoryID,
+		},
+	}
+	for _, pipeline := range pipelines {
+		pipelineView := PipelineView{
+			ID:     pipeline.ID,
+			Name:   pipeline.Name,
+			Issues: make([]IssueView, 0, len(pipeline.Issues)),
+		}
+		for _, issue := range pipeline.Issues {
+			issueView := IssueView{
+				IssueNumber: issue.IssueNumber,
+				Title:       titles.Title(issue.IssueNumber),
+			}
+			if issue.Estimate != nil {
+				issueView.Estimate = &issue.Estimate.Value
+			}
+			pipelineView.Issues = append(pipelineView.Issues, issueView)
+		}
+		view.Pipelines = append(view.Pipelines, pipelineView)
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(view)
+	}
+
+	return PrintBoardView(os.Stdout, view)
+}
+
+// PrintBoardView writes the board as a section per pipeline, with the
+// issues in it aligned in columns.
+func PrintBoardView(w io.Writer, view BoardView) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for i, pipeline := range view.Pipelines {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s (%s)\n", pipeline.Name, pipeline.ID)
+		if len(pipeline.Issues) == 0 {
+			fmt.Fprintln(tw, "  no issues")
+			continue
+		}
+		for _, issue := range pipeline.Issues {
+			estimate := "-"
+			if issue.Estimate != nil {
+				estimate = strconv.Itoa(*issue.Estimate)
+			}
+			fmt.Fprintf(tw, "  #%d\t%s\t%s\n", issue.IssueNumber, issue.Title, estimate)
+		}
+	}
+	return tw.Flush()
+}
+
+// issueTitleLookup looks up issue titles on GitHub for the board command.
+// After the first failed lookup, e.g. because GitHub's rate limit was hit,
+// no more lookups are made and titles are left empty.
+type issueTitleLookup struct {
+	ctx          *cli.Context
+	client       *GitHubClient
+	repositoryID uint
+	enabled      bool
+}
+
+func newIssueTitleLookup(ctx *cli.Context, repositoryID uint, enabled bool) *issueTitleLookup {
+	return &issueTitleLookup{ctx: ctx, repositoryID: repositoryID, enabled: enabled}
+}
+
+// Title returns the title of the issue with the given number, or an empty
+// string if it couldn't be looked up.
+func (l *issueTitleLookup) Title(issueNumber int) string {
+	if !l.enabled {
+		return ""
+	}
+
+	if l.client == nil {
+		client, err := NewGitHubClientFromContext(l.ctx)
+		if err != nil {
+			logrus.WithField("error", err).Warn("Failed to look up issue titles on GitHub, showing the board without them")
+			l.enabled = false
+			return ""
+		}
+		l.client = client
+	}
+
+	title, err := l.client.GetIssueTitle(l.repositoryID, issueNumber)
+	if err != nil {
+		logrus.WithField("error", err).Warn("Failed to look up issue titles on GitHub, showing the board without them")
+		l.enabled = false
+		return ""
+	}
+	return title
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// bashCompletionScript is the bash completion script printed by
+// completion bash, adapted from the one shipped with urfave/cli.
+const bashCompletionScript = `_zh_bash_autocomplete() {
+  if [[ "${COMP_WORDS[0]}" != "source" ]]; then
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == "-"* ]]; then
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} ${cur} --generate-bash-completion )
+    else
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion )
+    fi
+    COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+    return 0
+  fi
+}
+
+complete -o bashdefault -o default -o nospace -F _zh_bash_autocomplete zh
+`
+
+// zshCompletionScript is the zsh completion script printed by completion
+// zsh, adapted from the one shipped with urfave/cli.
+const zshCompletionScript = `#compdef zh
+
+_zh_zsh_autocomplete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _zh_zsh_autocomplete zh
+`
+
+// CompletionCommand prints a completion script for the given shell, to be
+// sourced from the shell's startup file.
+func CompletionCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the shell. Received %d", ctx.Args().Len())
+	}
+
+	switch shell := ctx.Args().First(); shell {
+	case "bash":
+		fmt.Print(bashCompletionScript)
+	case "zsh":
+		fmt.Print(zshCompletionScript)
+	default:
+		return fmt.Errorf("unsupported shell %s, expected bash or zsh", shell)
+	}
+
+	return nil
+}
+
+// CompletePipelineIDs completes the pipeline argument of issue mv with the
+// IDs of the pipelines in the workspace. The issue numbers before it can't be
+// completed, so nothing is offered until one has been given.
+//
+// Completion must never get in the way, so without a token, or if listing
+// the pipelines fails for any other reason, nothing is offered.
+func CompletePipelineIDs(ctx *cli.Context) {
+	if ctx.NArg() == 0 {
+		return
+	}
+	if _, err := GetZenHubToken(ctx.String("token-file")); err != nil {
+		return
+	}
+
+	pipelines, err := ListPipelines(ctx)
+	if err != nil {
+		return
+	}
+
+	// zsh can show the pipeline name as a description of each ID.
+	zsh := os.Getenv("_CLI_ZSH_AUTOCOMPLETE_HACK") == "1"
+	for _, pipeline := range pipelines {
+		if zsh {
+			fmt.Printf("%s:%s\n", pipeline.ID, pipeline.Name)
+		} else {
+			fmt.Println(pipeline.ID)
+		}
+	}
+}
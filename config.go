@@ -0,0 +1,440 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileName is the name of the config file within the zh config
+// directory.
+const ConfigFileName string = "config.yaml"
+
+// Config is the defaults set by the config file. Empty fields aren't set.
+type Config struct {
+	BaseURL      string `yaml:"base_url"`
+	WorkspaceID  string `yaml:"workspace_id"`
+	RepositoryID uint   `yaml:"repository_id"`
+	Token        string `yaml:"token"`
+
+	// Messages overrides the messages commands print.
+	Messages MessagesConfig `yaml:"messages"`
+
+	// PipelineAliases maps nicknames for pipelines, matched ignoring case, to
+	// the pipeline names or IDs they stand for.
+	PipelineAliases map[string]string `yaml:"pipeline_aliases"`
+
+	// GitHubProject is how `issue mv --sync-github-project` mirrors
+	// pipelines to a GitHub project.
+	GitHubProject GitHubProjectConfig `yaml:"github_project"`
+
+	// Profile is the name of the profile used when none is given by flag or
+	// environment variable.
+	Profile string `yaml:"profile"`
+
+	// Profiles are the named profiles, each overriding the top level
+	// settings it sets.
+	Profiles map[string]Config `yaml:"profiles"`
+}
+
+// GitHubProjectConfig maps pipelines to the options of a GitHub project's
+// status field.
+type GitHubProjectConfig struct {
+	// StatusField is the name of the project's single select field
+	// pipelines are mirrored to. It defaults to
+	// `DefaultGitHubProjectStatusField`.
+	StatusField string `yaml:"status_field"`
+
+	// Statuses maps pipeline names, matched ignoring case, or IDs to the
+	// names of the status options they are mirrored to. Pipelines that
+	// aren't mapped are mirrored to the option named like them.
+	Statuses map[string]string `yaml:"statuses"`
+}
+
+// WithProfile returns the config with the named profile's settings applied
+// over the top level ones. An empty name returns the config unchanged.
+func (c Config) WithProfile(name string) (Config, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return Config{}, fmt.Errorf("profile %s not found, the config file defines no profiles", name)
+		}
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Config{}, fmt.Errorf("profile %s not found, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	if profile.BaseURL != "" {
+		c.BaseURL = profile.BaseURL
+	}
+	if profile.WorkspaceID != "" {
+		c.WorkspaceID = profile.WorkspaceID
+	}
+	if profile.RepositoryID != 0 {
+		c.RepositoryID = profile.RepositoryID
+	}
+	if profile.Token != "" {
+		c.Token = profile.Token
+	}
+	if profile.Messages.MoveSuccess != "" {
+		c.Messages.MoveSuccess = profile.Messages.MoveSuccess
+	}
+	if profile.GitHubProject.StatusField != "" {
+		c.GitHubProject.StatusField = profile.GitHubProject.StatusField
+	}
+	if len(profile.GitHubProject.Statuses) > 0 {
+		statuses := make(map[string]string, len(c.GitHubProject.Statuses)+len(profile.GitHubProject.Statuses))
+		for pipeline, status := range c.GitHubProject.Statuses {
+			statuses[pipeline] = status
+		}
+		for pipeline, status := range profile.GitHubProject.Statuses {
+			statuses[pipeline] = status
+		}
+		c.GitHubProject.Statuses = statuses
+	}
+	if len(profile.PipelineAliases) > 0 {
+		aliases := make(map[string]string, len(c.PipelineAliases)+len(profile.PipelineAliases))
+		for alias, pipeline := range c.PipelineAliases {
+			aliases[alias] = pipeline
+		}
+		for alias, pipeline := range profile.PipelineAliases {
+			aliases[alias] = pipeline
+		}
+		c.PipelineAliases = aliases
+	}
+	c.Profile = name
+	return c, nil
+}
+
+// PipelineAlias returns the pipeline name or ID the given alias stands for,
+// matching it ignoring case, and whether it is an alias at all.
+func (c Config) PipelineAlias(alias string) (string, bool) {
+	for name, pipeline := range c.PipelineAliases {
+		if strings.EqualFold(name, alias) {
+			return pipeline, true
+		}
+	}
+	return "", false
+}
+
+// fileConfig is the config loaded from the config file by `SetupConfig`,
+// with the selected profile applied.
+var fileConfig Config
+
+// fileConfigPath is the path of the config file read by `SetupConfig`.
+var fileConfigPath string
+
+// SetupConfig loads the config file given by the `config` flag, or the one
+// at `ConfigPath` if it isn't set, and applies the profile given by the
+// `profile` flag or the config file's default profile.
+//
+// The config file's settings are defaults for the settings that weren't
+// given by flag or environment variable. The base URL is applied to the
+// `base-url` flag here, the other settings are fallbacks of the functions
+// resolving them.
+func SetupConfig(ctx *cli.Context) error {
+	path, explicit, err := configFlagPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	config, err := LoadConfig(path, explicit)
+	if err != nil {
+		return err
+	}
+	fileConfigPath = path
+
+	name := strings.TrimSpace(ctx.String("profile"))
+	if name == "" {
+		name = config.Profile
+	}
+	config, err = config.WithProfile(name)
+	if err != nil {
+		return err
+	}
+	fileConfig = config
+	if name != "" {
+		logrus.WithField("profile", name).Debug("Using config profile")
+	}
+
+	if err := SetupMessages(config.Messages); err != nil {
+		return fmt.Errorf("invalid messages in config file %s: %w", path, err)
+	}
+
+	if config.BaseURL != "" && !ctx.IsSet("base-url") && strings.TrimSpace(os.Getenv(ZenHubBaseURLEnvVar)) == "" {
+		if err := ctx.Set("base-url", config.BaseURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configFlagPath returns the path of the config file given by the `config`
+// flag, or the one at `ConfigPath` if it isn't set, and whether it was given.
+func configFlagPath(ctx *cli.Context) (string, bool, error) {
+	path := strings.TrimSpace(ctx.String("config"))
+	if path != "" {
+		return path, true, nil
+	}
+	path, err := ConfigPath()
+	return path, false, err
+}
+
+// ConfigPath returns the path of the config file,
+// `$XDG_CONFIG_HOME/zh/config.yaml`, falling back to `~/.config` if
+// `XDG_CONFIG_HOME` isn't set.
+func ConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find config directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "zh", ConfigFileName), nil
+}
+
+// LoadConfig reads the config file at the given path. A missing config file
+// is not an error, it just sets no defaults, unless its path was given
+// explicitly.
+func LoadConfig(path string, explicit bool) (Config, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && explicit {
+		return Config{}, fmt.Errorf("config file %s not found", path)
+	}
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to open config file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	config, err := ParseConfig(file)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses a YAML config file. Unknown keys are errors, so typos
+// don't go unnoticed.
+func ParseConfig(r io.Reader) (Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(r)
+	decoder.SetStrict(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+
+	if err := validatePipelineAliases(config.PipelineAliases); err != nil {
+		return Config{}, err
+	}
+	for name, profile := range config.Profiles {
+		if err := validatePipelineAliases(profile.PipelineAliases); err != nil {
+			return Config{}, fmt.Errorf("profile %s: %w", name, err)
+		}
+		if strings.TrimSpace(name) == "" {
+			return Config{}, errors.New("profile names can't be empty")
+		}
+		if profile.Profile != "" || profile.Profiles != nil {
+			return Config{}, fmt.Errorf("profile %s: profile and profiles can only be set at the top level", name)
+		}
+	}
+	return config, nil
+}
+
+// validatePipelineAliases checks no alias is empty or stands for an empty
+// pipeline, and that no two aliases differ only by case.
+func validatePipelineAliases(aliases map[string]string) error {
+	seen := make(map[string]string, len(aliases))
+	for alias, pipeline := range aliases {
+		if strings.TrimSpace(alias) == "" {
+			return errors.New("pipeline_aliases: aliases can't be empty")
+		}
+		if strings.TrimSpace(pipeline) == "" {
+			return fmt.Errorf("pipeline_aliases: alias %s stands for no pipeline", alias)
+		}
+		if other, ok := seen[strings.ToLower(alias)]; ok {
+			return fmt.Errorf("pipeline_aliases: aliases %s and %s only differ by case", other, alias)
+		}
+		seen[strings.ToLower(alias)] = alias
+	}
+	return nil
+}
+
+// Setting is a required setting that can be given by flag, environment
+// variable or config file, described so errors for it missing can explain
+// how to set it.
+type Setting struct {
+	Flag      string
+	EnvVar    string
+	ConfigKey string
+	Example   string
+
+	// Alternative is another flag that can be given instead, including an
+	// example value, if any.
+	Alternative string
+}
+
+var (
+	// WorkspaceIDSetting is the setting of the target workspace.
+	WorkspaceIDSetting = Setting{
+		Flag:        "workspace-id",
+		EnvVar:      ZenHubWorkspaceIDEnvVar,
+		ConfigKey:   "workspace_id",
+		Example:     "5c9f2a6b1e3d4f0a7b8c9d0e",
+		Alternative: "--workspace 'My Workspace'",
+	}
+
+	// RepositoryIDSetting is the setting of the target repository.
+	RepositoryIDSetting = Setting{
+		Flag:        "repository-id",
+		EnvVar:      ZenHubRepositoryIDEnvVar,
+		ConfigKey:   "repository_id",
+		Example:     "123456789",
+		Alternative: "--repo owner/name",
+	}
+)
+
+// MissingError returns the error for the setting not being set, followed by
+// `reason` if it isn't empty, e.g. why it couldn't be inferred instead. The
+// error lists every way of setting it with an example value.
+func (s Setting) MissingError(reason string) error {
+	if reason != "" {
+		reason = " and " + reason
+	}
+
+	configFile := "the config file"
+	if fileConfigPath != "" {
+		configFile = fileConfigPath
+	} else if path, err := ConfigPath(); err == nil {
+		configFile = path
+	}
+
+	ways := fmt.Sprintf("--%s %s", s.Flag, s.Example)
+	if s.Alternative != "" {
+		ways += ", " + s.Alternative
+	}
+	return fmt.Errorf("%s not set%s. Set it with %s, the %s environment variable (e.g. %s=%s) or %s: %s in %s",
+		s.Flag, reason, ways, s.EnvVar, s.EnvVar, s.Example, s.ConfigKey, s.configExample(), configFile)
+}
+
+// configExample returns the example value as it is written in the config
+// file.
+func (s Setting) configExample() string {
+	if _, err := strconv.ParseUint(s.Example, 10, 0); err == nil {
+		return s.Example
+	}
+	return strconv.Quote(s.Example)
+}
+
+// configTemplate is the config file `config edit` creates when there is none,
+// with every setting commented out.
+const configTemplate = `# zh config file. Flags and environment variables take precedence over it.
+
+# Base URL to build API endpoints from.
+# base_url: https://api.zenhub.com
+
+# ID of the target workspace and repository.
+# workspace_id: "5c9f2a6b1e3d4f0a7b8c9d0e"
+# repository_id: 123456789
+
+# ZenHub API token.
+# token: ""
+
+# Messages printed by commands.
+# messages:
+#   move_success: "Moved issue {{.IssueNumber}} to {{.PipelineID}}"
+
+# Nicknames for pipelines, matched ignoring case, and the pipeline names or
+# IDs they stand for.
+# pipeline_aliases:
+#   ip: In Progress
+
+# How issue mv --sync-github-project mirrors pipelines to the project's
+# status field. Pipelines that aren't mapped use the status named like them.
+# github_project:
+#   status_field: Status
+#   statuses:
+#     In Progress: Doing
+
+# Profile used when none is given by --profile or ZENHUB_PROFILE, and the
+# profiles, each overriding the settings above it sets.
+# profile: work
+# profiles:
+#   work:
+#     workspace_id: "5c9f2a6b1e3d4f0a7b8c9d0e"
+`
+
+// EditorEnvVars are the environment variables naming the editor `config
+// edit` opens, in order of precedence.
+var EditorEnvVars = []string{"VISUAL", "EDITOR"}
+
+// EditConfigCommand opens the config file given by the `config` flag, or the
+// one at `ConfigPath`, in the user's editor, creating it from
+// `configTemplate` if it doesn't exist. Once the editor exits, the file is
+// read again and any error in it is warned about.
+func EditConfigCommand(ctx *cli.Context) error {
+	var editor []string
+	for _, envVar := range EditorEnvVars {
+		if editor = strings.Fields(os.Getenv(envVar)); len(editor) > 0 {
+			break
+		}
+	}
+	if len(editor) == 0 {
+		return fmt.Errorf("no editor set, set %s or %s to the command to edit the config file with", EditorEnvVars[0], EditorEnvVars[1])
+	}
+
+	path, _, err := configFlagPath(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create config directory %s: %w", filepath.Dir(path), err)
+		}
+		// The config file may hold a token, so only the user can read it.
+		if err := ioutil.WriteFile(path, []byte(configTemplate), 0600); err != nil {
+			return fmt.Errorf("failed to create config file %s: %w", path, err)
+		}
+		logrus.WithField("path", path).Info("Created config file")
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to edit config file %s with %s: %w", path, editor[0], err)
+	}
+
+	config, err := LoadConfig(path, true)
+	if err == nil {
+		_, err = config.WithProfile(config.Profile)
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"path":  path,
+			"error": err,
+		}).Warn("Config file is invalid, zh will fail to start until it is fixed")
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nick96/zh/pkg/zenhub"
+)
+
+func TestPipelineAliases(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader(`
+pipeline_aliases:
+  wip: In Progress
+  done: Closed
+profiles:
+  work:
+    pipeline_aliases:
+      done: Shipped
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	if pipeline, ok := config.PipelineAlias("WIP"); !ok || pipeline != "In Progress" {
+		t.Errorf("expected WIP to stand for In Progress, got %q, %t", pipeline, ok)
+	}
+	if _, ok := config.PipelineAlias("backlog"); ok {
+		t.Error("expected backlog to not be an alias")
+	}
+
+	work, err := config.WithProfile("work")
+	if err != nil {
+		t.Fatalf("failed to apply profile: %v", err)
+	}
+	if pipeline, _ := work.PipelineAlias("done"); pipeline != "Shipped" {
+		t.Errorf("expected the profile's done alias for Shipped, got %q", pipeline)
+	}
+	if pipeline, _ := work.PipelineAlias("wip"); pipeline != "In Progress" {
+		t.Errorf("expected the top level wip alias to be kept, got %q", pipeline)
+	}
+	if pipeline, _ := config.PipelineAlias("done"); pipeline != "Closed" {
+		t.Errorf("expected applying the profile to leave the config's aliases alone, got %q", pipeline)
+	}
+}
+
+func TestInvalidPipelineAliases(t *testing.T) {
+	for name, contents := range map[string]string{
+		"empty pipeline":  "pipeline_aliases:\n  wip: ''\n",
+		"case duplicates": "pipeline_aliases:\n  wip: a\n  WIP: b\n",
+		"in a profile":    "profiles:\n  work:\n    pipeline_aliases:\n      wip: ' '\n",
+		"not a mapping":   "pipeline_aliases: wip\n",
+	} {
+		if _, err := ParseConfig(strings.NewReader(contents)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMatchPipelineArg(t *testing.T) {
+	index := zenhub.NewBoardIndex(&zenhub.Board{Pipelines: []zenhub.Pipeline{
+		{ID: "p1", Name: "Backlog"},
+		{ID: "p2", Name: "In Progress"},
+		// A pipeline named like another's ID is matched by name first.
+		{ID: "p3", Name: "p1"},
+	}})
+
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{target: "backlog", want: "p1"},
+		{target: "p2", want: "p2"},
+		{target: "p1", want: "p3"},
+		{target: "prog", want: "p2"},
+		{target: "missing", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := MatchPipelineArg(index, test.target)
+		if (err != nil) != test.wantErr {
+			t.Errorf("MatchPipelineArg(%q): expected error %t, got: %v", test.target, test.wantErr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("MatchPipelineArg(%q): expected %s, got %s", test.target, test.want, got)
+		}
+	}
+}
+
+// editorScript writes a shell script that overwrites the file it is given
+// with `contents`, to stand in for the user's editor.
+func editorScript(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "editor")
+	script := "#!/bin/sh\ncat > \"$1\" <<'EOF'\n" + contents + "EOF\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatalf("failed to write editor script: %v", err)
+	}
+	return path
+}
+
+func TestEditConfigCommand(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "zh", ConfigFileName)
+
+	setenv(t, "VISUAL", "")
+	setenv(t, "EDITOR", "true")
+	if err := runApp(t, server, "config", "edit"); err != nil {
+		t.Fatalf("failed to edit config: %v", err)
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the config file to be created: %v", err)
+	}
+	if string(contents) != configTemplate {
+		t.Errorf("expected the config file to be the template, got:\n%s", contents)
+	}
+	if _, err := ParseConfig(strings.NewReader(configTemplate)); err != nil {
+		t.Errorf("expected the template to be a valid config file: %v", err)
+	}
+
+	logs := captureLogs(t)
+	setenv(t, "EDITOR", editorScript(t, "workspace: typo\n"))
+	if err := runApp(t, server, "config", "edit"); err != nil {
+		t.Fatalf("expected an invalid config file to be warned about, got: %v", err)
+	}
+	if !strings.Contains(logs.String(), "Config file is invalid") {
+		t.Errorf("expected a warning for the invalid config file, got: %s", logs)
+	}
+
+	// The broken config file doesn't stop it being fixed.
+	logs.Reset()
+	setenv(t, "VISUAL", editorScript(t, "workspace_id: ws1\n"))
+	if err := runApp(t, server, "config", "edit"); err != nil {
+		t.Fatalf("failed to fix config: %v", err)
+	}
+	if strings.Contains(logs.String(), "Config file is invalid") {
+		t.Errorf("expected the fixed config file to be valid, got: %s", logs)
+	}
+	if contents, _ := ioutil.ReadFile(path); string(contents) != "workspace_id: ws1\n" {
+		t.Errorf("expected $VISUAL to take precedence over $EDITOR, got:\n%s", contents)
+	}
+
+	setenv(t, "VISUAL", "")
+	setenv(t, "EDITOR", "")
+	if err := runApp(t, server, "config", "edit"); err == nil || !strings.Contains(err.Error(), "no editor set") {
+		t.Errorf("expected an error without an editor, got: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CurlCommand returns a `curl` command equivalent to a ZenHub API request
+// with the given method, URL and JSON body.
+//
+// The authentication token is never included, the command reads it from
+// `ZenHubTokenEnvVar` instead so it can be shared safely.
+func CurlCommand(method, url string, body []byte) string {
+	args := []string{
+		"curl",
+		"-X", method,
+		// Double quoted so the shell expands the token variable.
+		"-H", fmt.Sprintf(`"%s: $%s"`, AuthenticationHeader, ZenHubTokenEnvVar),
+	}
+	if len(body) > 0 {
+		args = append(args,
+			"-H", shellQuote("Content-Type: application/json"),
+			"-d", shellQuote(string(body)),
+		)
+	}
+	args = append(args, shellQuote(url))
+	return strings.Join(args, " ")
+}
+
+// shellQuote single quotes the given string for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
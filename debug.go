@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DebugTransport is a custom transport that logs each request sent through
+// the wrapped `transport` and its response in full: the request line,
+// headers and body of both.
+//
+// Headers carrying the token are redacted, so it is safe to enable in CI.
+type DebugTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip logs the request, calls the wrapped `transport` and logs the
+// response. Bodies are read in full to log them and replaced so they can
+// still be read by the caller.
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		requestBody = body
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"request": req.Method + " " + req.URL.String() + " " + req.Proto,
+		"headers": formatHeaders(req.Header),
+		"body":    string(requestBody),
+	}).Info("HTTP request")
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		logrus.WithField("error", err).Info("HTTP request failed")
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	logrus.WithFields(logrus.Fields{
+		"status":  resp.Status,
+		"headers": formatHeaders(resp.Header),
+		"body":    string(responseBody),
+	}).Info("HTTP response")
+
+	return resp, nil
+}
+
+// formatHeaders formats headers as `Name: value` pairs in name order, with
+// the values of headers carrying the token redacted.
+func formatHeaders(header http.Header) string {
+	header = RedactHeaders(header)
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range header[name] {
+			pairs = append(pairs, name+": "+value)
+		}
+	}
+	return strings.Join(pairs, "; ")
+}
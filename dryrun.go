@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+)
+
+// DryRunTransport is a custom transport that logs requests which would
+// change something on ZenHub or GitHub instead of sending them, answering them with an
+// empty successful response. Requests that only read, i.e. GET requests and
+// GraphQL queries, are still sent through the wrapped `transport` so
+// commands can resolve IDs and check the board as usual.
+type DryRunTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip sends read-only requests through the wrapped `transport` and
+// logs all others without sending them.
+func (t *DryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		read, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = read
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	isGraphQL := strings.HasSuffix(req.URL.Path, zenhub.GraphQLPath) || req.URL.String() == GitHubBaseURL+GitHubGraphQLPath
+	if req.Method == http.MethodGet || req.Method == http.MethodHead || (isGraphQL && !isGraphQLMutation(body)) {
+		return t.transport.RoundTrip(req)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"method": req.Method,
+		"url":    req.URL.String(),
+		"body":   string(body),
+	}).Info("Dry run, not sending request")
+
+	responseBody := "{}"
+	if isGraphQL {
+		responseBody = `{"data":{}}`
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       req,
+	}, nil
+}
+
+// isGraphQLMutation reports whether the given GraphQL request body is a
+// mutation, rather than a query that only reads.
+func isGraphQLMutation(body []byte) bool {
+	var request zenhub.GraphQLRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		// Err on the side of not sending requests we don't understand.
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(request.Query), "mutation")
+}
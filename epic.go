@@ -0,0 +1,484 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/urfave/cli/v2"
+)
+
+// ListEpicsCommand lists the epics of the repository, sorted by the `sort`
+// flag. The `repository-id` flag of epic ls can list the epics of several
+// repositories together, adding a column for the repository of each.
+//
+// ZenHub only knows epics by their issue number, their titles live on
+// GitHub, so the issue URL is printed alongside the number instead. Titles
+// are only looked up to sort by them, and each epic's issues only to count
+// them or show progress, as they take a request per epic.
+func ListEpicsCommand(ctx *cli.Context) error {
+	withProgress := ctx.Bool("with-progress")
+	key, descending, err := ParseEpicSort(ctx.String("sort"), withProgress)
+	if err != nil {
+		return err
+	}
+
+	repositoryIDs, err := ResolveEpicRepositoryIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var summaries []EpicSummary
+	for _, repositoryID := range repositoryIDs {
+		epics, err := client.GetEpics(repositoryID)
+		if err != nil {
+			return fmt.Errorf("failed to list the epics of repository %d: %w", repositoryID, err)
+		}
+		for _, epic := range epics {
+			summaries = append(summaries, EpicSummary{
+				IssueNumber:  epic.IssueNumber,
+				RepositoryID: repositoryID,
+				IssueURL:     epic.IssueURL,
+			})
+		}
+	}
+
+	if key == EpicSortTitle {
+		github, err := NewGitHubClientFromContext(ctx)
+		if err != nil {
+			return err
+		}
+		for i := range summaries {
+			title, err := github.GetIssueTitle(summaries[i].RepositoryID, summaries[i].IssueNumber)
+			if err != nil {
+				return fmt.Errorf("failed to look up epic titles to sort by: %w", err)
+			}
+			summaries[i].Title = title
+		}
+	}
+
+	if key == EpicSortChildren || withProgress {
+		for i := range summaries {
+			epic, err := client.GetEpic(summaries[i].RepositoryID, summaries[i].IssueNumber)
+			if err != nil {
+				return err
+			}
+			children := len(epic.Issues)
+			summaries[i].Children = &children
+			if withProgress {
+				progress := EpicProgress(epic)
+				summaries[i].Progress = &progress
+			}
+		}
+	}
+
+	SortEpics(summaries, key, descending)
+
+	if IsStructuredOutput(ctx) {
+		if summaries == nil {
+			summaries = []EpicSummary{}
+		}
+		return PrintStructured(summaries)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, summary := range summaries {
+		var columns []string
+		if len(repositoryIDs) > 1 {
+			columns = append(columns, strconv.FormatUint(uint64(summary.RepositoryID), 10))
+		}
+		columns = append(columns, strconv.Itoa(summary.IssueNumber))
+		if key == EpicSortTitle {
+			columns = append(columns, summary.Title)
+		}
+		if summary.Children != nil {
+			columns = append(columns, fmt.Sprintf("%d issues", *summary.Children))
+		}
+		if summary.Progress != nil {
+			columns = append(columns, fmt.Sprintf("%.0f%%", *summary.Progress*100))
+		}
+		columns = append(columns, summary.IssueURL)
+		fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	}
+	return tw.Flush()
+}
+
+const (
+	// EpicSortIssueNumber sorts epics by their issue number.
+	EpicSortIssueNumber string = "issue_number"
+
+	// EpicSortTitle sorts epics by their title on GitHub.
+	EpicSortTitle string = "title"
+
+	// EpicSortChildren sorts epics by the number of issues in them.
+	EpicSortChildren string = "children"
+
+	// EpicSortProgress sorts epics by the share of their issues that are
+	// closed. It needs `with-progress`.
+	EpicSortProgress string = "progress"
+)
+
+// EpicSummary is an epic listed by epic ls. Only the details needed to sort
+// by or asked for with `with-progress` are filled in.
+type EpicSummary struct {
+	IssueNumber  int      `json:"issue_number"`
+	RepositoryID uint     `json:"repo_id"`
+	IssueURL     string   `json:"issue_url"`
+	Title        string   `json:"title,omitempty"`
+	Children     *int     `json:"children,omitempty"`
+	Progress     *float64 `json:"progress,omitempty"`
+}
+
+// ParseEpicSort parses the `sort` flag of epic ls: a sort key, optionally
+// followed by `:asc` or `:desc`. Sorting by progress needs `with-progress`,
+// which fetches it.
+func ParseEpicSort(value string, withProgress bool) (string, bool, error) {
+	key, descending, err := ParseSort(value, EpicSortIssueNumber, EpicSortTitle, EpicSortChildren, EpicSortProgress)
+	if err != nil {
+		return "", false, err
+	}
+	if key == EpicSortProgress && !withProgress {
+		return "", false, fmt.Errorf("sorting by %s needs with-progress to be set", EpicSortProgress)
+	}
+	return key, descending, nil
+}
+
+// SortEpics sorts the epics by the given key, breaking ties by issue number
+// and then repository so the order is the same from run to run.
+func SortEpics(epics []EpicSummary, key string, descending bool) {
+	compare := func(a, b EpicSummary) int {
+		switch key {
+		case EpicSortTitle:
+			return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case EpicSortChildren:
+			return compareInts(derefInt(a.Children), derefInt(b.Children))
+		case EpicSortProgress:
+			return compareFloats(derefFloat(a.Progress), derefFloat(b.Progress))
+		}
+		return 0
+	}
+
+	sort.SliceStable(epics, func(i, j int) bool {
+		c := compare(epics[i], epics[j])
+		if c == 0 {
+			c = compareInts(epics[i].IssueNumber, epics[j].IssueNumber)
+		}
+		if c == 0 {
+			c = compareInts(int(epics[i].RepositoryID), int(epics[j].RepositoryID))
+		}
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// ResolveEpicRepositoryIDs returns the repositories given by the
+// `repository-id` flag of epic ls, which can be repeated or list several IDs
+// separated by commas, or the one resolved by `ResolveRepositoryID` if it
+// isn't set. Repositories given more than once are only listed once.
+func ResolveEpicRepositoryIDs(ctx *cli.Context) ([]uint, error) {
+	values := ctx.StringSlice("repository-id")
+	if len(values) == 0 {
+		// The command's flag shadows the global one of the same name, so it
+		// is resolved from the parent command instead.
+		repositoryID, err := ResolveRepositoryID(ctx.Lineage()[1])
+		if err != nil {
+			return nil, err
+		}
+		return []uint{repositoryID}, nil
+	}
+
+	var repositoryIDs []uint
+	seen := make(map[uint]bool)
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			repositoryID, err := strconv.ParseUint(field, 10, 0)
+			if err != nil || repositoryID == 0 {
+				return nil, fmt.Errorf("invalid repository ID %q in repository-id, expected a positive number such as %s", field, RepositoryIDSetting.Example)
+			}
+			if !seen[uint(repositoryID)] {
+				seen[uint(repositoryID)] = true
+				repositoryIDs = append(repositoryIDs, uint(repositoryID))
+			}
+		}
+	}
+	return repositoryIDs, nil
+}
+
+// ClosedPipelineName is the pipeline ZenHub reports closed issues in.
+const ClosedPipelineName = "Closed"
+
+// EpicProgress returns the share, from 0 to 1, of the epic's issues that are
+// closed. An epic without issues has made no progress.
+func EpicProgress(epic *zenhub.EpicData) float64 {
+	if len(epic.Issues) == 0 {
+		return 0
+	}
+	closed := 0
+	for _, issue := range epic.Issues {
+		if issue.Pipeline.Name == ClosedPipelineName {
+			closed++
+		}
+	}
+	return float64(closed) / float64(len(epic.Issues))
+}
+
+// EpicInfo is the details of an epic shown by epic show.
+type EpicInfo struct {
+	EpicNumber    int             `json:"epic_number"`
+	Estimate      *int            `json:"estimate"`
+	TotalEstimate *int            `json:"total_estimate"`
+	PipelineID    string          `json:"pipeline_id"`
+	PipelineName  string          `json:"pipeline_name"`
+	Issues        []EpicIssueInfo `json:"issues"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// EpicIssueInfo is an issue in an epic shown by epic show.
+type EpicIssueInfo struct {
+	IssueNumber  int    `json:"issue_number"`
+	RepositoryID uint   `json:"repository_id"`
+	Estimate     *int   `json:"estimate"`
+	IsEpic       bool   `json:"is_epic"`
+	PipelineID   string `json:"pipeline_id"`
+	PipelineName string `json:"pipeline_name"`
+}
+
+// ShowEpicCommand prints an epic's pipeline, its estimate and the total
+// estimate of its issues, and the pipeline and estimate of each of its
+// issues.
+func ShowEpicCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the epic's issue number. Received %d", ctx.Args().Len())
+	}
+
+	epicID, err := ParseIssueNumber(ctx.Args().First())
+	if err != nil {
+		return fmt.Errorf("invalid epic: %w", err)
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	epic, err := client.GetEpic(repositoryID, epicID)
+	if err != nil {
+		if zenhub.HasStatusCode(err, 404) {
+			InvalidateRepositoryID(repositoryID)
+			return epicNotFoundError(epicID, repositoryID)
+		}
+		return err
+	}
+
+	info := EpicInfo{
+		EpicNumber:   epicID,
+		PipelineID:   epic.Pipeline.PipelineID,
+		PipelineName: epic.Pipeline.Name,
+		Issues:       make([]EpicIssueInfo, 0, len(epic.Issues)),
+	}
+	if epic.Estimate != nil {
+		info.Estimate = &epic.Estimate.Value
+	}
+	if epic.TotalEstimate != nil {
+		info.TotalEstimate = &epic.TotalEstimate.Value
+	}
+	for _, issue := range epic.Issues {
+		issueInfo := EpicIssueInfo{
+			IssueNumber:  issue.IssueNumber,
+			RepositoryID: issue.RepositoryID,
+			IsEpic:       issue.IsEpic,
+			PipelineID:   issue.Pipeline.PipelineID,
+			PipelineName: issue.Pipeline.Name,
+		}
+		if issue.Estimate != nil {
+			issueInfo.Estimate = &issue.Estimate.Value
+		}
+		info.Issues = append(info.Issues, issueInfo)
+	}
+
+	if IsStructuredOutput(ctx) {
+		info.Resolved = ResolvedIDs{
+			WorkspaceID:  epic.Pipeline.WorkspaceID,
+			RepositoryID: repositoryID,
+			PipelineID:   epic.Pipeline.PipelineID,
+		}
+		return PrintStructured(info)
+	}
+
+	fmt.Printf("Epic:           %d\n", info.EpicNumber)
+	fmt.Printf("Pipeline:       %s (%s)\n", info.PipelineName, info.PipelineID)
+	fmt.Printf("Estimate:       %s\n", formatEstimate(info.Estimate))
+	fmt.Printf("Total estimate: %s\n", formatEstimate(info.TotalEstimate))
+	if len(info.Issues) == 0 {
+		fmt.Println("Issues:         none")
+		return nil
+	}
+	fmt.Println("Issues:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, issue := range info.Issues {
+		number := fmt.Sprintf("#%d", issue.IssueNumber)
+		if issue.RepositoryID != repositoryID {
+			number = fmt.Sprintf("%d#%d", issue.RepositoryID, issue.IssueNumber)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", number, issue.PipelineName, formatEstimate(issue.Estimate))
+	}
+	return tw.Flush()
+}
+
+// epicNotFoundError is the error for an epic the API reports as not found.
+func epicNotFoundError(epicID int, repositoryID uint) error {
+	return fmt.Errorf("epic %d not found in repository %d. Check that the issue exists and is an epic", epicID, repositoryID)
+}
+
+// EpicIssuesResult is the JSON output of adding issues to or removing
+// issues from an epic.
+type EpicIssuesResult struct {
+	EpicID   int   `json:"epic_id"`
+	IssueIDs []int `json:"issue_ids"`
+}
+
+// AddEpicIssuesCommand adds issues to an epic.
+func AddEpicIssuesCommand(ctx *cli.Context) error {
+	return updateEpicIssues(ctx, false)
+}
+
+// RemoveEpicIssuesCommand removes issues from an epic.
+func RemoveEpicIssuesCommand(ctx *cli.Context) error {
+	return updateEpicIssues(ctx, true)
+}
+
+// updateEpicIssues adds the issues given as arguments after the epic to the
+// epic or, if `remove` is set, removes them from it, in a single request.
+func updateEpicIssues(ctx *cli.Context, remove bool) error {
+	if ctx.Args().Len() < 2 {
+		return fmt.Errorf("expected at least two arguments, the epic's issue number and the issue numbers. Received %d", ctx.Args().Len())
+	}
+
+	epicID, err := ParseIssueNumber(ctx.Args().First())
+	if err != nil {
+		return fmt.Errorf("invalid epic: %w", err)
+	}
+
+	issueIDs, err := parseIssueNumbers(ctx.Args().Slice()[1:])
+	if err != nil {
+		return err
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	issues := make([]zenhub.EpicIssue, 0, len(issueIDs))
+	for _, issueID := range issueIDs {
+		issues = append(issues, zenhub.EpicIssue{RepositoryID: repositoryID, IssueNumber: issueID})
+	}
+	request := zenhub.UpdateEpicIssuesRequest{AddIssues: issues}
+	verb, preposition := "added", "to"
+	if remove {
+		request = zenhub.UpdateEpicIssuesRequest{RemoveIssues: issues}
+		verb, preposition = "removed", "from"
+	}
+
+	if err := client.UpdateEpicIssues(repositoryID, epicID, request); err != nil {
+		if zenhub.HasStatusCode(err, 404) {
+			InvalidateRepositoryID(repositoryID)
+			return epicNotFoundError(epicID, repositoryID)
+		}
+		return err
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(EpicIssuesResult{EpicID: epicID, IssueIDs: issueIDs})
+	}
+
+	if !IsQuiet(ctx) {
+		for _, issueID := range issueIDs {
+			fmt.Printf("Successfully %s issue %d %s epic %d\n", verb, issueID, preposition, epicID)
+		}
+	}
+
+	return nil
+}
+
+// ConvertToEpicCommand converts an issue to an epic, adding the issues
+// given after it to the new epic.
+func ConvertToEpicCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() < 1 {
+		return fmt.Errorf("expected at least one argument, the issue number to convert to an epic. Received %d", ctx.Args().Len())
+	}
+
+	issueIDs, err := parseIssueNumbers(ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+	epicID, issueIDs := issueIDs[0], issueIDs[1:]
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	issues := make([]zenhub.EpicIssue, 0, len(issueIDs))
+	for _, issueID := range issueIDs {
+		issues = append(issues, zenhub.EpicIssue{RepositoryID: repositoryID, IssueNumber: issueID})
+	}
+	if err := client.ConvertToEpic(repositoryID, epicID, issues); err != nil {
+		return err
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(EpicIssuesResult{EpicID: epicID, IssueIDs: issueIDs})
+	}
+
+	if !IsQuiet(ctx) {
+		fmt.Printf("Successfully converted issue %d to an epic\n", epicID)
+		for _, issueID := range issueIDs {
+			fmt.Printf("Successfully added issue %d to epic %d\n", issueID, epicID)
+		}
+	}
+
+	return nil
+}
+
+// parseIssueNumbers parses each of the given arguments with
+// `ParseIssueNumber`.
+func parseIssueNumbers(args []string) ([]int, error) {
+	issueIDs := make([]int, 0, len(args))
+	for _, arg := range args {
+		issueID, err := ParseIssueNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		issueIDs = append(issueIDs, issueID)
+	}
+	return issueIDs, nil
+}
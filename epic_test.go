@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEpicSort(t *testing.T) {
+	tests := []struct {
+		value          string
+		withProgress   bool
+		wantKey        string
+		wantDescending bool
+		wantErr        bool
+	}{
+		{value: "issue_number", wantKey: EpicSortIssueNumber},
+		{value: "title:asc", wantKey: EpicSortTitle},
+		{value: "children:desc", wantKey: EpicSortChildren, wantDescending: true},
+		{value: "progress", withProgress: true, wantKey: EpicSortProgress},
+		{value: "progress", wantErr: true},
+		{value: "title:up", wantErr: true},
+		{value: "name", wantErr: true},
+	}
+
+	for _, test := range tests {
+		key, descending, err := ParseEpicSort(test.value, test.withProgress)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseEpicSort(%q): expected error %t, got: %v", test.value, test.wantErr, err)
+			continue
+		}
+		if key != test.wantKey || descending != test.wantDescending {
+			t.Errorf("ParseEpicSort(%q): expected %s descending %t, got %s descending %t",
+				test.value, test.wantKey, test.wantDescending, key, descending)
+		}
+	}
+}
+
+func TestSortEpics(t *testing.T) {
+	one, three := 1, 3
+	half, all := 0.5, 1.0
+	epics := []EpicSummary{
+		{IssueNumber: 3, Title: "banana", Children: &one, Progress: &all},
+		{IssueNumber: 1, Title: "Cherry", Children: &three, Progress: &half},
+		{IssueNumber: 2, Title: "apple", Children: &one, Progress: &half},
+	}
+
+	tests := []struct {
+		key        string
+		descending bool
+		want       []int
+	}{
+		{key: EpicSortIssueNumber, want: []int{1, 2, 3}},
+		{key: EpicSortIssueNumber, descending: true, want: []int{3, 2, 1}},
+		{key: EpicSortTitle, want: []int{2, 3, 1}},
+		{key: EpicSortChildren, want: []int{2, 3, 1}},
+		{key: EpicSortProgress, descending: true, want: []int{3, 2, 1}},
+	}
+
+	for _, test := range tests {
+		sorted := append([]EpicSummary(nil), epics...)
+		SortEpics(sorted, test.key, test.descending)
+		var got []int
+		for _, epic := range sorted {
+			got = append(got, epic.IssueNumber)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SortEpics by %s descending %t: expected %v, got %v", test.key, test.descending, test.want, got)
+		}
+	}
+}
+
+func TestListEpicsCommandRepositories(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var repositoryID uint
+		fmt.Sscanf(r.URL.Path, "/p1/repositories/%d/epics", &repositoryID)
+		fmt.Fprintf(w, `{"epic_issues": [{"issue_number": %d, "repo_id": %d, "issue_url": "https://github.com/o/r%d/issues/%d"}]}`,
+			repositoryID/100, repositoryID, repositoryID, repositoryID/100)
+	})
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runApp(t, server, "--repository-id", "999", "epic", "ls", "--repository-id", "300,100", "--repository-id", "200", "--repository-id", "100")
+	})
+	if err != nil {
+		t.Fatalf("failed to list epics: %v", err)
+	}
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{
+		"100 1 https://github.com/o/r100/issues/1",
+		"200 2 https://github.com/o/r200/issues/2",
+		"300 3 https://github.com/o/r300/issues/3",
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("expected the epics of each repository once with a repository column %v, got %v", want, rows)
+	}
+	for _, request := range server.Requests() {
+		if strings.Contains(request.Path, "/999/") {
+			t.Errorf("expected the global repository to be overridden, got request %s", request.Path)
+		}
+	}
+
+	output = captureStdout(t, func() {
+		err = runApp(t, server, "--repository-id", "100", "epic", "ls")
+	})
+	if err != nil {
+		t.Fatalf("failed to list epics: %v", err)
+	}
+	if got := strings.Join(strings.Fields(output), " "); got != "1 https://github.com/o/r100/issues/1" {
+		t.Errorf("expected the global repository without a repository column, got %q", got)
+	}
+
+	for _, value := range []string{"abc", "100,", "0"} {
+		err = runApp(t, server, "epic", "ls", "--repository-id", value)
+		if err == nil || !strings.Contains(err.Error(), "invalid repository ID") {
+			t.Errorf("expected --repository-id %s to be invalid, got: %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// IssueEstimate is the estimate of an issue, if it has one.
+type IssueEstimate struct {
+	IssueNumber int  `json:"issue_number"`
+	Estimate    *int `json:"estimate"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// GetEstimateCommand prints the current estimate of an issue.
+func GetEstimateCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the issue number. Received %d", ctx.Args().Len())
+	}
+
+	issueID, err := ParseIssueNumber(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	issue, err := client.GetIssueData(repositoryID, issueID)
+	if err != nil {
+		return err
+	}
+
+	estimate := IssueEstimate{IssueNumber: issueID}
+	if issue.Estimate != nil {
+		estimate.Estimate = &issue.Estimate.Value
+	}
+
+	if IsStructuredOutput(ctx) {
+		estimate.Resolved = ResolvedIDs{
+			WorkspaceID:  issue.Pipeline.WorkspaceID,
+			RepositoryID: repositoryID,
+			PipelineID:   issue.Pipeline.PipelineID,
+		}
+		return PrintStructured(estimate)
+	}
+
+	if estimate.Estimate == nil {
+		fmt.Println("none")
+	} else {
+		fmt.Println(*estimate.Estimate)
+	}
+
+	return nil
+}
+
+// SetEstimateCommand sets the estimate of an issue.
+func SetEstimateCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("expected exactly two arguments, the issue number and the estimate. Received %d", ctx.Args().Len())
+	}
+
+	issueID, err := ParseIssueNumber(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	value, err := strconv.Atoi(ctx.Args().Get(1))
+	if err != nil || value < 0 {
+		return fmt.Errorf("expected estimate to be a non-negative int, got %s", ctx.Args().Get(1))
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.Bool("force") {
+		if err := ValidateEstimate(ctx, client, repositoryID, value); err != nil {
+			return err
+		}
+	}
+
+	if err := client.SetEstimate(repositoryID, issueID, value); err != nil {
+		if zenhub.HasStatusCode(err, 404) {
+			InvalidateRepositoryID(repositoryID)
+			return fmt.Errorf("issue %d not found in repository %d", issueID, repositoryID)
+		}
+		return err
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(IssueEstimate{
+			IssueNumber: issueID,
+			Estimate:    &value,
+			Resolved:    ResolvedIDs{RepositoryID: repositoryID},
+		})
+	}
+
+	if !IsQuiet(ctx) {
+		fmt.Printf("Successfully set estimate of issue %d to %d\n", issueID, value)
+	}
+
+	return nil
+}
+
+// ValidateEstimate checks the given estimate is one of the values allowed by
+// the workspace's estimate scale.
+//
+// The values are only available through the GraphQL API, so the estimate is
+// only validated when the client uses it and the workspace can be resolved.
+// Otherwise, or if the values can't be looked up, the estimate is assumed to
+// be valid and ZenHub is left to reject it.
+func ValidateEstimate(ctx *cli.Context, client *zenhub.Client, repositoryID uint, value int) error {
+	if client.API() != zenhub.APIGraphQL {
+		logrus.WithField("api", client.API()).Debug("Estimate values are only available through the GraphQL API, not validating the estimate")
+		return nil
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		logrus.WithField("error", err).Debug("Failed to resolve the workspace, not validating the estimate")
+		return nil
+	}
+
+	values, err := client.GetEstimateValues(workspaceID)
+	if err != nil {
+		logrus.WithField("error", err).Warn("Failed to get the workspace's estimate values, not validating the estimate")
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	allowed := make([]string, 0, len(values))
+	for _, allowedValue := range values {
+		if allowedValue == float64(value) {
+			return nil
+		}
+		allowed = append(allowed, strconv.FormatFloat(allowedValue, 'f', -1, 64))
+	}
+	return fmt.Errorf("estimate %d is not allowed in workspace %s, expected one of %s. Use --force to set it anyway",
+		value, workspaceID, strings.Join(allowed, ", "))
+}
+
+const (
+	// EstimateStatusCleared is the status of an issue whose estimate was
+	// cleared.
+	EstimateStatusCleared string = "cleared"
+
+	// EstimateStatusFailed is the status of an issue whose estimate failed
+	// to be cleared.
+	EstimateStatusFailed string = "failed"
+)
+
+// ClearedEstimate is the structured output of clearing an issue's estimate,
+// one per issue.
+type ClearedEstimate struct {
+	IssueNumber int    `json:"issue_number"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ClearEstimateCommand removes the estimate from one or more issues.
+func ClearEstimateCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() == 0 {
+		return fmt.Errorf("expected at least one argument, the issue number. Received %d", ctx.Args().Len())
+	}
+
+	issueIDs := make([]int, 0, ctx.Args().Len())
+	for _, arg := range ctx.Args().Slice() {
+		issueID, err := ParseIssueNumber(arg)
+		if err != nil {
+			return err
+		}
+		issueIDs = append(issueIDs, issueID)
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, issueID := range issueIDs {
+		result := ClearedEstimate{IssueNumber: issueID, Status: EstimateStatusCleared}
+		if err := client.ClearEstimate(repositoryID, issueID); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"issue_id": issueID,
+				"error":    err,
+			}).Error("Failed to clear estimate")
+			failed++
+			result.Status, result.Error = EstimateStatusFailed, err.Error()
+		}
+
+		switch {
+		case IsStructuredOutput(ctx):
+			if err := PrintStructured(result); err != nil {
+				return err
+			}
+		case result.Status == EstimateStatusCleared && !IsQuiet(ctx):
+			fmt.Printf("Successfully cleared estimate of issue %d\n", issueID)
+		}
+	}
+
+	if len(issueIDs) > 1 && !IsStructuredOutput(ctx) && !IsQuiet(ctx) {
+		fmt.Printf("Cleared %d of %d estimates\n", len(issueIDs)-failed, len(issueIDs))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to clear %d of %d estimates", failed, len(issueIDs))
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+)
+
+// OutputCSV is the output format for CSV output, only supported by export.
+const OutputCSV string = "csv"
+
+// ExportedIssue is the pipeline and estimate of an issue in a board export.
+type ExportedIssue struct {
+	IssueNumber  int    `json:"issue_number" yaml:"issue_number"`
+	PipelineID   string `json:"pipeline_id" yaml:"pipeline_id"`
+	PipelineName string `json:"pipeline_name" yaml:"pipeline_name"`
+	Position     int    `json:"position" yaml:"position"`
+	Estimate     *int   `json:"estimate" yaml:"estimate"`
+}
+
+// exportCSVHeader is the header row of a CSV export.
+var exportCSVHeader = []string{"issue_number", "pipeline_id", "pipeline_name", "position", "estimate"}
+
+// ExportCommand writes the pipeline and estimate of every issue on the board
+// as JSON, YAML or CSV, e.g. as a backup before a reorganisation.
+//
+// The format is given by the `format` flag, falling back to the `output`
+// flag if it is a structured format and JSON otherwise.
+func ExportCommand(ctx *cli.Context) (err error) {
+	format := ctx.String("format")
+	if format == "" {
+		format = OutputJSON
+		if IsStructuredOutput(ctx) {
+			format = outputFormat
+		}
+	}
+	if format != OutputJSON && format != OutputYAML && format != OutputCSV {
+		return fmt.Errorf("invalid format value of %s, expected one of %s, %s or %s", format, OutputJSON, OutputYAML, OutputCSV)
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	var issues []ExportedIssue
+	for _, pipeline := range board.Pipelines {
+		for i, issue := range pipeline.Issues {
+			exported := ExportedIssue{
+				IssueNumber:  issue.IssueNumber,
+				PipelineID:   pipeline.ID,
+				PipelineName: pipeline.Name,
+				Position:     i,
+			}
+			if issue.Estimate != nil {
+				value := issue.Estimate.Value
+				exported.Estimate = &value
+			}
+			issues = append(issues, exported)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if path := ctx.String("output-file"); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create export file %s: %w", path, err)
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to write export file %s: %w", path, closeErr)
+			}
+		}()
+		w = file
+	}
+
+	switch format {
+	case OutputCSV:
+		err = WriteExportCSV(w, issues)
+	case OutputYAML:
+		err = WriteExportYAML(w, issues)
+	default:
+		err = WriteExportJSON(w, issues)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	if path := ctx.String("output-file"); path != "" && !IsQuiet(ctx) {
+		fmt.Fprintf(os.Stderr, "Exported %d issues to %s\n", len(issues), path)
+	}
+
+	return nil
+}
+
+// WriteExportJSON writes the exported issues as a JSON array.
+func WriteExportJSON(w io.Writer, issues []ExportedIssue) error {
+	if issues == nil {
+		issues = []ExportedIssue{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+// WriteExportYAML writes the exported issues as a YAML document with a list
+// of issues.
+func WriteExportYAML(w io.Writer, issues []ExportedIssue) error {
+	if issues == nil {
+		issues = []ExportedIssue{}
+	}
+	body, err := MarshalYAML(issues)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "---\n%s", body)
+	return err
+}
+
+// WriteExportCSV writes the exported issues as CSV with a header row.
+func WriteExportCSV(w io.Writer, issues []ExportedIssue) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportCSVHeader); err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		estimate := ""
+		if issue.Estimate != nil {
+			estimate = strconv.Itoa(*issue.Estimate)
+		}
+		record := []string{
+			strconv.Itoa(issue.IssueNumber),
+			issue.PipelineID,
+			issue.PipelineName,
+			strconv.Itoa(issue.Position),
+			estimate,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fixture is a recorded response to a request.
+type Fixture struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// FixtureTransport is a custom transport that either records the responses
+// of the wrapped `transport` to fixture files in `dir`, or replays responses
+// from those files without touching the network.
+//
+// Fixtures are keyed by the request's method, URL and body, since every
+// GraphQL request is a POST to the same URL.
+type FixtureTransport struct {
+	transport http.RoundTripper
+	dir       string
+	replay    bool
+}
+
+// FixturePath returns the path of the fixture file for the given request
+// with the given body. Requests without a body are keyed by their URL alone,
+// so fixtures recorded before bodies were part of the key still replay.
+func (t *FixtureTransport) FixturePath(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.URL.String()))
+	if len(body) > 0 {
+		hash.Write([]byte{0})
+		hash.Write(body)
+	}
+	name := fmt.Sprintf("%s_%s.json", strings.ToLower(req.Method), hex.EncodeToString(hash.Sum(nil)[:8]))
+	return filepath.Join(t.dir, name)
+}
+
+// RoundTrip replays the fixture for the request if `replay` is set,
+// otherwise it calls the wrapped `transport` and records the response. The
+// request body is read to key the fixture and replaced so it can still be
+// sent.
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		read, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = read
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	path := t.FixturePath(req, body)
+	if t.replay {
+		return t.replayFixture(req, path)
+	}
+	return t.recordFixture(req, path)
+}
+
+func (t *FixtureTransport) replayFixture(req *http.Request, path string) (*http.Response, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture recorded for %s %s: %w", req.Method, req.URL, err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(contents, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"url":     req.URL.String(),
+		"fixture": path,
+	}).Debug("Replaying fixture")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.StatusCode, http.StatusText(fixture.StatusCode)),
+		StatusCode:    fixture.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fixture.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(fixture.Body)),
+		ContentLength: int64(len(fixture.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *FixtureTransport) recordFixture(req *http.Request, path string) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body to record: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	fixture := Fixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	}
+	contents, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert fixture to JSON: %w", err)
+	}
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory %s: %w", t.dir, err)
+	}
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write fixture %s: %w", path, err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"url":     req.URL.String(),
+		"fixture": path,
+	}).Debug("Recorded fixture")
+	return resp, nil
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	// GitHubBaseURL is the base URL of the GitHub API, used to look up
+	// repository IDs and issue titles.
+	GitHubBaseURL string = "https://api.github.com"
+
+	// GitHubTokenEnvVar is the environment variable to retrieve the GitHub
+	// token from. It is optional, but without it GitHub only allows a
+	// handful of unauthenticated requests and no private repositories.
+	GitHubTokenEnvVar string = "GITHUB_TOKEN"
+)
+
+// RepositoryCacheFileName is the name of the file within the zh state
+// directory the IDs of repositories looked up on GitHub are cached in.
+const RepositoryCacheFileName string = "repositories.json"
+
+// repositoryIDCache holds the IDs of repositories already looked up on
+// GitHub, keyed by their lower cased `owner/name`. It is loaded from the
+// cache file the first time it is needed, so each repository is only looked
+// up once across invocations.
+var repositoryIDCache = struct {
+	sync.Mutex
+	loaded bool
+	ids    map[string]uint
+}{}
+
+// ResolveRepositoryID returns the ID of the target repository. An explicit
+// `repository-id` flag takes precedence, then the `repository` flag's
+// `owner/name` is looked up on GitHub, then the default `repository-id` from
+// the environment or config file is used.
+func ResolveRepositoryID(ctx *cli.Context) (uint, error) {
+	if repositoryID := ctx.Uint("repository-id"); ctx.IsSet("repository-id") && repositoryID != 0 {
+		return repositoryID, nil
+	}
+
+	if fullName := strings.TrimSpace(ctx.String("repository")); fullName != "" {
+		return ResolveGitHubRepositoryID(ctx, fullName)
+	}
+
+	if repositoryID := ctx.Uint("repository-id"); repositoryID != 0 {
+		return repositoryID, nil
+	}
+	if fileConfig.RepositoryID != 0 {
+		return fileConfig.RepositoryID, nil
+	}
+	return 0, RepositoryIDSetting.MissingError("")
+}
+
+// ResolveGitHubRepositoryID looks up the ID of the repository with the given
+// `owner/name` on GitHub.
+func ResolveGitHubRepositoryID(ctx *cli.Context, fullName string) (uint, error) {
+	client, err := NewGitHubClientFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return client.GetRepositoryID(fullName)
+}
+
+// GitHubClient is a client of the GitHub API, authenticating with
+// `GitHubTokenEnvVar` if it is set.
+//
+// Requests go through the transport from `NewTransport`, so they are
+// recorded, replayed, logged and dry run like requests to ZenHub, and each
+// has the `timeout` flag's time to complete.
+type GitHubClient struct {
+	httpClient *http.Client
+	ctx        context.Context
+	timeout    time.Duration
+}
+
+// NewGitHubClientFromContext creates the GitHub client used by commands.
+func NewGitHubClientFromContext(ctx *cli.Context) (*GitHubClient, error) {
+	transport, err := NewTransport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubClient{
+		httpClient: &http.Client{Transport: transport},
+		ctx:        ctx.Context,
+		timeout:    ctx.Duration("timeout"),
+	}, nil
+}
+
+// IssueReference is an issue given on the command line, either by number or
+// by its GitHub URL.
+type IssueReference struct {
+	IssueNumber int
+
+	// Repository is the `owner/name` of the repository from the issue's
+	// URL, or empty if it was given by number.
+	Repository string
+}
+
+// ParseIssueNumber parses an issue number as GitHub shows it, within its
+// repository, optionally prefixed with `#`. ZenHub identifies issues by
+// these numbers rather than by an ID of its own.
+func ParseIssueNumber(arg string) (int, error) {
+	issueNumber, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || issueNumber <= 0 {
+		return 0, fmt.Errorf("invalid issue number %s, expected a positive integer such as 42 or #42", arg)
+	}
+	return issueNumber, nil
+}
+
+// ParseIssueReference parses an issue number, as accepted by
+// `ParseIssueNumber`, or a GitHub issue URL such as
+// `https://github.com/nick96/zh/issues/42`.
+func ParseIssueReference(arg string) (IssueReference, error) {
+	if _, err := strconv.Atoi(strings.TrimPrefix(arg, "#")); err == nil {
+		issueNumber, err := ParseIssueNumber(arg)
+		if err != nil {
+			return IssueReference{}, err
+		}
+		return IssueReference{IssueNumber: issueNumber}, nil
+	}
+
+	invalid := fmt.Errorf("invalid issue %s, expected an issue number or a GitHub issue URL such as https://github.com/owner/name/issues/42", arg)
+	parsed, err := url.Parse(arg)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return IssueReference{}, invalid
+	}
+	if host := strings.ToLower(parsed.Host); host != "github.com" && host != "www.github.com" {
+		return IssueReference{}, invalid
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] == "" || parts[1] == "" || (parts[2] != "issues" && parts[2] != "pull") {
+		return IssueReference{}, invalid
+	}
+	issueNumber, err := strconv.Atoi(parts[3])
+	if err != nil || issueNumber <= 0 {
+		return IssueReference{}, invalid
+	}
+
+	return IssueReference{IssueNumber: issueNumber, Repository: parts[0] + "/" + parts[1]}, nil
+}
+
+// GetRepositoryID looks up the ID of the repository with the given
+// `owner/name` on GitHub. IDs are cached in the state directory, since they
+// don't change when a repository is renamed.
+//
+// The cache is only locked while it is read and written, not while GitHub is
+// asked, so concurrent lookups of different repositories don't wait on each
+// other.
+func (c *GitHubClient) GetRepositoryID(fullName string) (uint, error) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return 0, fmt.Errorf("invalid repository value of %s, expected owner/name", fullName)
+	}
+
+	key := strings.ToLower(fullName)
+	if repositoryID := cachedRepositoryID(key); repositoryID != 0 {
+		logrus.WithFields(logrus.Fields{
+			"repository":    fullName,
+			"repository_id": repositoryID,
+		}).Debug("Resolved repository ID from the cache")
+		return repositoryID, nil
+	}
+
+	url := fmt.Sprintf("%s/repos/%s/%s", GitHubBaseURL, parts[0], parts[1])
+	logrus.WithField("url", url).Debug("Sending get GitHub repository request")
+	resp, err := c.send(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get repository %s from GitHub: %w", fullName, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+	case 401:
+		return 0, fmt.Errorf("failed to get repository %s from GitHub: token is not valid. Check that %s is set correctly", fullName, GitHubTokenEnvVar)
+	case 404:
+		// A concurrent lookup may have cached the repository before it was
+		// deleted.
+		invalidateRepositoryIDs(func(name string, _ uint) bool { return name == key })
+		return 0, fmt.Errorf("failed to get repository %s from GitHub: not found. Private repositories need %s to be set", fullName, GitHubTokenEnvVar)
+	default:
+		return 0, fmt.Errorf("failed to get repository %s from GitHub: unexpected status code %d", fullName, resp.StatusCode)
+	}
+
+	var repository struct {
+		ID uint `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&repository); err != nil {
+		return 0, fmt.Errorf("failed to decode GitHub repository %s: %w", fullName, err)
+	}
+	if repository.ID == 0 {
+		return 0, fmt.Errorf("failed to get repository %s from GitHub: no ID returned", fullName)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"repository":    fullName,
+		"repository_id": repository.ID,
+	}).Debug("Resolved repository ID from GitHub")
+	cacheRepositoryID(key, repository.ID)
+
+	return repository.ID, nil
+}
+
+// RefreshRepositoryID looks up the ID of the repository with the given
+// `owner/name` on GitHub like `GetRepositoryID`, ignoring any cached ID, e.g.
+// because it disagrees with an ID given explicitly.
+func (c *GitHubClient) RefreshRepositoryID(fullName string) (uint, error) {
+	key := strings.ToLower(fullName)
+	invalidateRepositoryIDs(func(name string, _ uint) bool { return name == key })
+	return c.GetRepositoryID(fullName)
+}
+
+// InvalidateRepositoryID removes the repositories cached with the given ID,
+// so they are looked up on GitHub again next time. It is called when ZenHub
+// doesn't find a repository by a possibly cached ID, as the repository may
+// have been deleted and recreated under the same name with a new ID.
+func InvalidateRepositoryID(repositoryID uint) {
+	invalidateRepositoryIDs(func(_ string, id uint) bool { return id == repositoryID })
+}
+
+// cachedRepositoryID returns the cached ID of the repository with the given
+// lower cased `owner/name`, or 0 if it isn't cached.
+func cachedRepositoryID(key string) uint {
+	repositoryIDCache.Lock()
+	defer repositoryIDCache.Unlock()
+	ensureRepositoryCacheLoaded()
+	return repositoryIDCache.ids[key]
+}
+
+// cacheRepositoryID caches the ID of the repository with the given lower
+// cased `owner/name` and saves the cache.
+func cacheRepositoryID(key string, repositoryID uint) {
+	repositoryIDCache.Lock()
+	defer repositoryIDCache.Unlock()
+	ensureRepositoryCacheLoaded()
+	repositoryIDCache.ids[key] = repositoryID
+	if err := saveRepositoryCache(repositoryIDCache.ids); err != nil {
+		logrus.WithField("error", err).Warn("Failed to save repository cache")
+	}
+}
+
+// invalidateRepositoryIDs removes the cached repositories `match` reports
+// true for and saves the cache if any were removed.
+func invalidateRepositoryIDs(match func(key string, repositoryID uint) bool) {
+	repositoryIDCache.Lock()
+	defer repositoryIDCache.Unlock()
+	ensureRepositoryCacheLoaded()
+
+	removed := false
+	for key, repositoryID := range repositoryIDCache.ids {
+		if match(key, repositoryID) {
+			logrus.WithFields(logrus.Fields{
+				"repository":    key,
+				"repository_id": repositoryID,
+			}).Debug("Invalidated cached repository ID")
+			delete(repositoryIDCache.ids, key)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	if err := saveRepositoryCache(repositoryIDCache.ids); err != nil {
+		logrus.WithField("error", err).Warn("Failed to save repository cache")
+	}
+}
+
+// ensureRepositoryCacheLoaded loads the cache file the first time the cache
+// is used. The cache must be locked.
+func ensureRepositoryCacheLoaded() {
+	if repositoryIDCache.loaded {
+		return
+	}
+	repositoryIDCache.loaded = true
+	ids, err := loadRepositoryCache()
+	if err != nil {
+		logrus.WithField("error", err).Warn("Ignoring unreadable repository cache")
+	}
+	repositoryIDCache.ids = ids
+}
+
+// loadRepositoryCache reads the repository cache file. A missing file is an
+// empty cache.
+func loadRepositoryCache() (map[string]uint, error) {
+	ids := map[string]uint{}
+	path, err := StatePath(RepositoryCacheFileName)
+	if err != nil {
+		return ids, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return ids, fmt.Errorf("failed to read repository cache %s: %w", path, err)
+	}
+	if err := json.Unmarshal(contents, &ids); err != nil {
+		return map[string]uint{}, fmt.Errorf("failed to decode repository cache %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// saveRepositoryCache writes the repository cache file, replacing any
+// previous one.
+func saveRepositoryCache(ids map[string]uint) error {
+	path, err := StatePath(RepositoryCacheFileName)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert repository cache to JSON: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write repository cache %s: %w", path, err)
+	}
+	return nil
+}
+
+// GitHubIssueStateClosed is the state of a closed GitHub issue.
+const GitHubIssueStateClosed = "closed"
+
+// GitHubIssue is the part of a GitHub issue zh uses.
+type GitHubIssue struct {
+	NodeID    string        `json:"node_id"`
+	Title     string        `json:"title"`
+	State     string        `json:"state"`
+	Assignees []GitHubUser  `json:"assignees"`
+	Labels    []GitHubLabel `json:"labels"`
+}
+
+// GitHubUser is the part of a GitHub user zh uses.
+type GitHubUser struct {
+	Login string `json:"login"`
+}
+
+// GitHubLabel is the part of a GitHub label zh uses.
+type GitHubLabel struct {
+	Name string `json:"name"`
+}
+
+// HasLabel reports whether the issue has the label with the given name,
+// matched ignoring case as GitHub label names are.
+func (i *GitHubIssue) HasLabel(name string) bool {
+	for _, label := range i.Labels {
+		if strings.EqualFold(label.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssigneeMe is the assignee that stands for the user GITHUB_TOKEN belongs
+// to, as in GitHub's own search.
+const AssigneeMe = "@me"
+
+// IsAssignedTo reports whether the issue is assigned to the user with the
+// given login, matched ignoring case as GitHub logins are.
+func (i *GitHubIssue) IsAssignedTo(login string) bool {
+	for _, assignee := range i.Assignees {
+		if strings.EqualFold(assignee.Login, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAuthenticatedUser looks up the user GITHUB_TOKEN belongs to.
+func (c *GitHubClient) GetAuthenticatedUser() (*GitHubUser, error) {
+	url := GitHubBaseURL + "/user"
+	logrus.WithField("url", url).Debug("Sending get authenticated GitHub user request")
+	resp, err := c.send(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the authenticated user from GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := gitHubResponseError(resp, "get the authenticated user"); err != nil {
+		return nil, err
+	}
+
+	var user GitHubUser
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode the authenticated GitHub user: %w", err)
+	}
+	return &user, nil
+}
+
+// ResolveAssignee resolves the `assignee` flag to a GitHub login, looking up
+// the user GITHUB_TOKEN belongs to for `AssigneeMe`. A leading `@` on a
+// login is dropped. It returns an empty login if no assignee was given.
+func ResolveAssignee(github *GitHubClient, assignee string) (string, error) {
+	assignee = strings.TrimSpace(assignee)
+	if assignee != AssigneeMe {
+		return strings.TrimPrefix(assignee, "@"), nil
+	}
+	if strings.TrimSpace(os.Getenv(GitHubTokenEnvVar)) == "" {
+		return "", fmt.Errorf("assignee %s needs %s to be set to look up who it is", AssigneeMe, GitHubTokenEnvVar)
+	}
+	user, err := github.GetAuthenticatedUser()
+	if err != nil {
+		return "", err
+	}
+	logrus.WithField("login", user.Login).Debug("Resolved assignee")
+	return user.Login, nil
+}
+
+// GetIssueTitle looks up the title of the issue with the given number in the
+// repository with the given ID on GitHub.
+func (c *GitHubClient) GetIssueTitle(repositoryID uint, issueNumber int) (string, error) {
+	issue, err := c.GetIssue(repositoryID, issueNumber)
+	if err != nil {
+		return "", err
+	}
+	return issue.Title, nil
+}
+
+// GetIssue looks up the issue with the given number in the repository with
+// the given ID on GitHub.
+func (c *GitHubClient) GetIssue(repositoryID uint, issueNumber int) (*GitHubIssue, error) {
+	url := fmt.Sprintf("%s/repositories/%d/issues/%d", GitHubBaseURL, repositoryID, issueNumber)
+	logrus.WithField("url", url).Debug("Sending get GitHub issue request")
+	resp, err := c.send(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue %d from GitHub: %w", issueNumber, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+	case 401:
+		return nil, fmt.Errorf("failed to get issue %d from GitHub: token is not valid. Check that %s is set correctly", issueNumber, GitHubTokenEnvVar)
+	case 404:
+		return nil, fmt.Errorf("failed to get issue %d from GitHub: not found. Private repositories need %s to be set", issueNumber, GitHubTokenEnvVar)
+	default:
+		return nil, fmt.Errorf("failed to get issue %d from GitHub: unexpected status code %d", issueNumber, resp.StatusCode)
+	}
+
+	var issue GitHubIssue
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return nil, fmt.Errorf("failed to decode GitHub issue %d: %w", issueNumber, err)
+	}
+
+	return &issue, nil
+}
+
+// send sends a request to the given GitHub API URL, encoding `body` as JSON
+// if it isn't nil. The caller is responsible for closing the body of the
+// returned response.
+func (c *GitHubClient) send(method, url string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert request %v to JSON: %w", body, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	ctx := zenhub.WithAttemptTimeout(c.ctx, c.timeout)
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := strings.TrimSpace(os.Getenv(GitHubTokenEnvVar)); token != "" {
+		redactionHook.AddSecret(token)
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && c.ctx.Err() == nil {
+			return nil, fmt.Errorf("request timed out after %s", c.timeout)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
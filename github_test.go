@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withGitHubServer points the GitHub API at a test server with the given
+// handler and the state directory at an empty temporary one, with the
+// repository cache reset. It returns a client of the server.
+func withGitHubServer(t *testing.T, handler http.HandlerFunc) *GitHubClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	baseURL := GitHubBaseURL
+	GitHubBaseURL = server.URL
+	t.Cleanup(func() { GitHubBaseURL = baseURL })
+
+	stateHome, set := os.LookupEnv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Cleanup(func() {
+		if set {
+			os.Setenv("XDG_STATE_HOME", stateHome)
+		} else {
+			os.Unsetenv("XDG_STATE_HOME")
+		}
+	})
+
+	repositoryIDCache.Lock()
+	repositoryIDCache.loaded = false
+	repositoryIDCache.ids = nil
+	repositoryIDCache.Unlock()
+
+	return &GitHubClient{httpClient: server.Client(), ctx: context.Background()}
+}
+
+// repositoryHandler answers GitHub repository requests with the ID in `ids`
+// for the repository's lower cased `owner/name`, or 404 if it has none,
+// counting the requests. Requests are expected one at a time.
+func repositoryHandler(ids map[string]uint, requests *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		id, ok := ids[strings.ToLower(strings.TrimPrefix(r.URL.Path, "/repos/"))]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"id": %d}`, id)
+	}
+}
+
+func TestGetRepositoryIDCaches(t *testing.T) {
+	var requests int32
+	client := withGitHubServer(t, repositoryHandler(map[string]uint{"nick96/zh": 42}, &requests))
+
+	for i := 0; i < 2; i++ {
+		id, err := client.GetRepositoryID("Nick96/ZH")
+		if err != nil {
+			t.Fatalf("failed to get repository ID: %v", err)
+		}
+		if id != 42 {
+			t.Errorf("expected repository ID 42, got %d", id)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request to GitHub, got %d", requests)
+	}
+
+	// The cache file is read by later invocations.
+	repositoryIDCache.Lock()
+	repositoryIDCache.loaded = false
+	repositoryIDCache.Unlock()
+	if id, err := client.GetRepositoryID("nick96/zh"); err != nil || id != 42 {
+		t.Errorf("expected repository ID 42 from the cache file, got %d: %v", id, err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request to GitHub, got %d", requests)
+	}
+}
+
+func TestGetRepositoryIDInvalidation(t *testing.T) {
+	var requests int32
+	ids := map[string]uint{"nick96/zh": 42}
+	client := withGitHubServer(t, repositoryHandler(ids, &requests))
+
+	if _, err := client.GetRepositoryID("nick96/zh"); err != nil {
+		t.Fatalf("failed to get repository ID: %v", err)
+	}
+
+	// The repository is recreated with a new ID, which ZenHub doesn't find
+	// the old ID for.
+	ids["nick96/zh"] = 43
+	InvalidateRepositoryID(42)
+	if id, err := client.GetRepositoryID("nick96/zh"); err != nil || id != 43 {
+		t.Errorf("expected repository ID 43 after invalidating, got %d: %v", id, err)
+	}
+
+	ids["nick96/zh"] = 44
+	if id, err := client.RefreshRepositoryID("nick96/zh"); err != nil || id != 44 {
+		t.Errorf("expected repository ID 44 after refreshing, got %d: %v", id, err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests to GitHub, got %d", requests)
+	}
+
+	delete(ids, "nick96/zh")
+	if _, err := client.RefreshRepositoryID("nick96/zh"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got: %v", err)
+	}
+	if id := cachedRepositoryID("nick96/zh"); id != 0 {
+		t.Errorf("expected the deleted repository to not be cached, got %d", id)
+	}
+}
+
+func TestGetRepositoryIDDoesNotLockAcrossRequests(t *testing.T) {
+	// The first repository's lookup only completes once the second's has
+	// started, which it can't while the cache is locked.
+	secondStarted := make(chan struct{})
+	client := withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/first":
+			select {
+			case <-secondStarted:
+			case <-time.After(5 * time.Second):
+				t.Error("second lookup didn't start while the first was in flight")
+			}
+			fmt.Fprint(w, `{"id": 1}`)
+		case "/repos/owner/second":
+			close(secondStarted)
+			fmt.Fprint(w, `{"id": 2}`)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"owner/first", "owner/second"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := client.GetRepositoryID(name); err != nil {
+				t.Errorf("failed to get repository ID of %s: %v", name, err)
+			}
+		}(name)
+		if name == "owner/first" {
+			// Give the first lookup time to reach the server.
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	wg.Wait()
+}
+
+func TestIssueMoverIsClosed(t *testing.T) {
+	client := withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repositories/1/issues/1":
+			fmt.Fprint(w, `{"title": "Open", "state": "open"}`)
+		case "/repositories/1/issues/2":
+			fmt.Fprint(w, `{"title": "Closed", "state": "closed"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mover := &IssueMover{github: client, repositoryID: 1}
+
+	for issueID, want := range map[int]bool{1: false, 2: true, 3: false} {
+		if got := mover.isClosed(issueID); got != want {
+			t.Errorf("isClosed(%d): expected %t, got %t", issueID, want, got)
+		}
+	}
+
+	// Without GitHub every issue is assumed to be open.
+	if (&IssueMover{repositoryID: 1}).isClosed(2) {
+		t.Error("expected issues to be assumed open without a GitHub client")
+	}
+}
+
+func TestParseIssueNumber(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    int
+		wantErr bool
+	}{
+		{arg: "42", want: 42},
+		{arg: "#42", want: 42},
+		{arg: "0", wantErr: true},
+		{arg: "-3", wantErr: true},
+		{arg: "#", wantErr: true},
+		{arg: "##42", wantErr: true},
+		{arg: "forty-two", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseIssueNumber(test.arg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseIssueNumber(%q): expected error %t, got: %v", test.arg, test.wantErr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseIssueNumber(%q): expected %d, got %d", test.arg, test.want, got)
+		}
+	}
+}
+
+func TestResolveAssignee(t *testing.T) {
+	var requests int32
+	client := withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/user" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"login": "nick96"}`)
+	})
+
+	setenv(t, GitHubTokenEnvVar, "")
+	if _, err := ResolveAssignee(client, AssigneeMe); err == nil || !strings.Contains(err.Error(), GitHubTokenEnvVar) {
+		t.Errorf("expected %s to need %s, got: %v", AssigneeMe, GitHubTokenEnvVar, err)
+	}
+
+	setenv(t, GitHubTokenEnvVar, "github_token")
+	tests := map[string]string{
+		"":         "",
+		AssigneeMe: "nick96",
+		"octocat":  "octocat",
+		"@octocat": "octocat",
+	}
+	for assignee, want := range tests {
+		got, err := ResolveAssignee(client, assignee)
+		if err != nil {
+			t.Errorf("ResolveAssignee(%q): %v", assignee, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ResolveAssignee(%q): expected %q, got %q", assignee, want, got)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected only %s to be looked up, got %d requests", AssigneeMe, requests)
+	}
+}
+
+func TestMoveIssueCommandAssignee(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			fmt.Fprint(w, `{"login": "nick96"}`)
+		case "/repositories/1/issues/1":
+			fmt.Fprint(w, `{"state": "open", "assignees": [{"login": "octocat"}, {"login": "Nick96"}]}`)
+		case "/repositories/1/issues/2":
+			fmt.Fprint(w, `{"state": "open", "assignees": [{"login": "octocat"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	setenv(t, GitHubTokenEnvVar, "github_token")
+
+	err := runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--assignee", AssigneeMe, "1", "2", testPipelineID)
+	if err != nil {
+		t.Fatalf("failed to move issues: %v", err)
+	}
+	var moved []string
+	for _, request := range server.Requests() {
+		if request.Method == http.MethodPost {
+			moved = append(moved, request.Path)
+		}
+	}
+	if want := []string{"/p2/workspaces/ws1/repositories/1/issues/1/moves"}; !reflect.DeepEqual(moved, want) {
+		t.Errorf("expected only the issue assigned to the user to move, got moves %v", moved)
+	}
+}
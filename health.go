@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// DefaultMaxLatency is the default latency budget of the health check.
+var DefaultMaxLatency time.Duration = 500 * time.Millisecond
+
+// HealthCheck is the result of a successful health check. Failed checks are
+// reported as errors.
+type HealthCheck struct {
+	URL          string  `json:"url"`
+	StatusCode   int     `json:"status_code"`
+	LatencyMS    float64 `json:"latency_ms"`
+	MaxLatencyMS float64 `json:"max_latency_ms"`
+}
+
+// HealthCommand checks that the ZenHub API is reachable within the latency
+// budget, returning an error (and so a non-zero exit code) if it is not.
+//
+// This is meant to be wrapped by monitoring probes so it deliberately doesn't
+// need a token: any response that isn't a server error counts as reachable.
+func HealthCommand(ctx *cli.Context) error {
+	maxLatency := ctx.Duration("max-latency")
+	if maxLatency <= 0 {
+		return fmt.Errorf("invalid max-latency value of %s", maxLatency)
+	}
+
+	url := ctx.String("base-url")
+	client := http.Client{Timeout: maxLatency}
+
+	req, err := http.NewRequestWithContext(ctx.Context, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	logrus.WithField("url", url).Debug("Sending health check request")
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return fmt.Errorf("ZenHub API at %s is unreachable within %s: %w", url, maxLatency, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("ZenHub API at %s is unhealthy: status code %d after %s", url, resp.StatusCode, latency)
+	}
+
+	if latency > maxLatency {
+		return fmt.Errorf("ZenHub API at %s responded in %s, over the budget of %s", url, latency, maxLatency)
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(HealthCheck{
+			URL:          url,
+			StatusCode:   resp.StatusCode,
+			LatencyMS:    float64(latency) / float64(time.Millisecond),
+			MaxLatencyMS: float64(maxLatency) / float64(time.Millisecond),
+		})
+	}
+
+	fmt.Printf("ZenHub API at %s is healthy (latency %s)\n", url, latency)
+
+	return nil
+}
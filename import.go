@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultImportConcurrency is the default number of issues updated in
+// parallel by import.
+var DefaultImportConcurrency uint = 4
+
+// importChange is what needs to change for an issue to match its export.
+type importChange struct {
+	issue        ExportedIssue
+	move         bool
+	fromPipeline string
+	setEstimate  bool
+	fromEstimate *int
+}
+
+// ImportResult is the structured output of import.
+type ImportResult struct {
+	Moved     int   `json:"moved"`
+	Estimated int   `json:"estimated"`
+	Unchanged int   `json:"unchanged"`
+	Failed    []int `json:"failed"`
+
+	// Planned are the changes that would be made, only set on a dry run.
+	Planned []PlannedImportChange `json:"planned,omitempty"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// PlannedImportChange is a change import would make on a dry run. The
+// pipelines are only set if the issue would be moved and the estimates only
+// if `SetEstimate` is.
+type PlannedImportChange struct {
+	IssueNumber    int    `json:"issue_number"`
+	FromPipelineID string `json:"from_pipeline_id,omitempty"`
+	ToPipelineID   string `json:"to_pipeline_id,omitempty"`
+	SetEstimate    bool   `json:"set_estimate"`
+	FromEstimate   *int   `json:"from_estimate,omitempty"`
+	ToEstimate     *int   `json:"to_estimate,omitempty"`
+}
+
+// ImportCommand applies a snapshot written by export, moving issues and
+// setting estimates until the board matches it. Issues that already match
+// are skipped.
+//
+// Moved issues are placed at the bottom of their pipeline. With a
+// concurrency of 1 they are moved in snapshot order, so the order within
+// each pipeline is restored too.
+func ImportCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the export file. Received %d", ctx.Args().Len())
+	}
+
+	issues, err := ReadExport(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	concurrency := ctx.Uint("concurrency")
+	if concurrency == 0 {
+		return fmt.Errorf("invalid concurrency value of %d", concurrency)
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	changes, skipped := planImport(zenhub.NewBoardIndex(board), issues)
+
+	resolved := ResolvedIDs{WorkspaceID: workspaceID, RepositoryID: repositoryID}
+	if ctx.Bool("dry-run") {
+		if IsStructuredOutput(ctx) {
+			result := ImportResult{Failed: []int{}, Unchanged: skipped, Planned: []PlannedImportChange{}, Resolved: resolved}
+			for _, change := range changes {
+				planned := PlannedImportChange{IssueNumber: change.issue.IssueNumber}
+				if change.move {
+					planned.FromPipelineID, planned.ToPipelineID = change.fromPipeline, change.issue.PipelineID
+				}
+				if change.setEstimate {
+					planned.SetEstimate, planned.FromEstimate, planned.ToEstimate = true, change.fromEstimate, change.issue.Estimate
+				}
+				result.Planned = append(result.Planned, planned)
+			}
+			return PrintStructured(result)
+		}
+		for _, change := range changes {
+			if change.move {
+				fmt.Printf("Would move issue %d from pipeline %s to %s\n",
+					change.issue.IssueNumber, change.fromPipeline, change.issue.PipelineID)
+			}
+			if change.setEstimate {
+				fmt.Printf("Would change estimate of issue %d from %s to %s\n",
+					change.issue.IssueNumber, formatEstimate(change.fromEstimate), formatEstimate(change.issue.Estimate))
+			}
+		}
+		fmt.Printf("Would change %d issues, %d already match\n", len(changes), skipped)
+		return nil
+	}
+
+	var (
+		mu        sync.Mutex
+		failed    []int
+		moved     int
+		estimated int
+		wg        sync.WaitGroup
+	)
+	work := make(chan importChange)
+	for i := uint(0); i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for change := range work {
+				didMove, didEstimate, err := applyImportChange(client, workspaceID, repositoryID, change)
+				mu.Lock()
+				if didMove {
+					moved++
+				}
+				if didEstimate {
+					estimated++
+				}
+				if err != nil {
+					logrus.WithFields(logrus.Fields{
+						"issue_id": change.issue.IssueNumber,
+						"error":    err,
+					}).Error("Failed to import issue")
+					failed = append(failed, change.issue.IssueNumber)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	// Once interrupted, the remaining changes are left unapplied.
+	for _, change := range changes {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- change
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Ints(failed)
+	switch {
+	case IsStructuredOutput(ctx):
+		result := ImportResult{Moved: moved, Estimated: estimated, Unchanged: skipped, Failed: failed, Resolved: resolved}
+		if result.Failed == nil {
+			result.Failed = []int{}
+		}
+		if err := PrintStructured(result); err != nil {
+			return err
+		}
+	case !IsQuiet(ctx):
+		fmt.Printf("Moved %d issues and changed %d estimates, %d issues already matched\n", moved, estimated, skipped)
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted after moving %d issues and changing %d estimates: %w", moved, estimated, ctx.Err())
+	}
+
+	if len(failed) > 0 {
+		numbers := make([]string, 0, len(failed))
+		for _, issueID := range failed {
+			numbers = append(numbers, strconv.Itoa(issueID))
+		}
+		return fmt.Errorf("failed to import %d issues: %s", len(failed), strings.Join(numbers, ", "))
+	}
+
+	return nil
+}
+
+// planImport works out the changes needed for the board to match the
+// exported issues, returning them along with the number of issues that
+// already match.
+func planImport(index *zenhub.BoardIndex, issues []ExportedIssue) ([]importChange, int) {
+	var changes []importChange
+	skipped := 0
+	for _, issue := range issues {
+		change := importChange{issue: issue}
+
+		pipeline, current := index.Issue(issue.IssueNumber)
+		if pipeline == nil || pipeline.ID != issue.PipelineID {
+			change.move = true
+			if pipeline != nil {
+				change.fromPipeline = pipeline.ID
+			}
+		}
+
+		var currentEstimate *int
+		if current != nil && current.Estimate != nil {
+			currentEstimate = &current.Estimate.Value
+		}
+		if !estimatesEqual(currentEstimate, issue.Estimate) {
+			change.setEstimate = true
+			change.fromEstimate = currentEstimate
+		}
+
+		if !change.move && !change.setEstimate {
+			skipped++
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes, skipped
+}
+
+// applyImportChange applies a single planned change, reporting which parts
+// of it were applied.
+func applyImportChange(client *zenhub.Client, workspaceID string, repositoryID uint, change importChange) (bool, bool, error) {
+	issueID := change.issue.IssueNumber
+
+	moved := false
+	if change.move {
+		request := zenhub.MoveIssueRequest{
+			PipelineID: change.issue.PipelineID,
+			Position:   "bottom",
+		}
+		if err := client.MoveIssue(workspaceID, repositoryID, issueID, request); err != nil {
+			return false, false, err
+		}
+		moved = true
+	}
+
+	if change.setEstimate {
+		var err error
+		if change.issue.Estimate == nil {
+			err = client.ClearEstimate(repositoryID, issueID)
+		} else {
+			err = client.SetEstimate(repositoryID, issueID, *change.issue.Estimate)
+		}
+		if err != nil {
+			return moved, false, err
+		}
+	}
+
+	return moved, change.setEstimate, nil
+}
+
+// ReadExport reads issues from a file written by export. CSV files are
+// recognised by their `.csv` extension and YAML files by `.yaml` or `.yml`,
+// anything else is read as JSON.
+func ReadExport(path string) ([]ExportedIssue, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		issues, err := ReadExportCSV(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export file %s: %w", path, err)
+		}
+		return issues, nil
+	}
+
+	var issues []ExportedIssue
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(file).Decode(&issues)
+	default:
+		err = json.NewDecoder(file).Decode(&issues)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export file %s: %w", path, err)
+	}
+	return issues, nil
+}
+
+// ReadExportCSV reads issues from CSV written by `WriteExportCSV`.
+func ReadExportCSV(r io.Reader) ([]ExportedIssue, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"issue_number", "pipeline_id"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("expected CSV column %s", name)
+		}
+	}
+
+	var issues []ExportedIssue
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %w", err)
+		}
+
+		issueID, err := strconv.Atoi(record[columns["issue_number"]])
+		if err != nil {
+			return nil, fmt.Errorf("expected issue_number to be an int, got %s", record[columns["issue_number"]])
+		}
+		issue := ExportedIssue{
+			IssueNumber: issueID,
+			PipelineID:  record[columns["pipeline_id"]],
+		}
+		if i, ok := columns["pipeline_name"]; ok {
+			issue.PipelineName = record[i]
+		}
+		if i, ok := columns["estimate"]; ok && record[i] != "" {
+			estimate, err := strconv.Atoi(record[i])
+			if err != nil {
+				return nil, fmt.Errorf("expected estimate of issue %d to be an int, got %s", issueID, record[i])
+			}
+			issue.Estimate = &estimate
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+func estimatesEqual(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func formatEstimate(estimate *int) string {
+	if estimate == nil {
+		return "none"
+	}
+	return strconv.Itoa(*estimate)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// IssuePosition is where an issue sits on the board, as reported by issue
+// position.
+type IssuePosition struct {
+	zenhub.IssuePosition
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// IssuePositionCommand reports the pipeline an issue is in and its
+// zero-based index within that pipeline.
+func IssuePositionCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the issue number. Received %d", ctx.Args().Len())
+	}
+
+	issueID, err := ParseIssueNumber(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	location, err := zenhub.NewBoardIndex(board).IssuePosition(issueID)
+	if err != nil {
+		return err
+	}
+	position := IssuePosition{IssuePosition: *location}
+
+	if IsStructuredOutput(ctx) {
+		position.Resolved = ResolvedIDs{
+			WorkspaceID:  workspaceID,
+			RepositoryID: repositoryID,
+			PipelineID:   position.PipelineID,
+		}
+		return PrintStructured(position)
+	}
+
+	fmt.Printf("Issue %d is at index %d of pipeline %s (%s)\n",
+		position.IssueNumber,
+		position.Index,
+		position.PipelineName,
+		position.PipelineID,
+	)
+
+	return nil
+}
+
+// IssueInfo is the details of an issue shown by issue info.
+type IssueInfo struct {
+	IssueNumber  int                      `json:"issue_number"`
+	Estimate     *int                     `json:"estimate"`
+	IsEpic       bool                     `json:"is_epic"`
+	PipelineID   string                   `json:"pipeline_id"`
+	PipelineName string                   `json:"pipeline_name"`
+	BlockedBy    []zenhub.DependencyIssue `json:"blocked_by"`
+	Blocking     []zenhub.DependencyIssue `json:"blocking"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// IssueInfoCommand prints an issue's pipeline, estimate, whether it is an
+// epic and the issues it blocks or is blocked by.
+func IssueInfoCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the issue number. Received %d", ctx.Args().Len())
+	}
+
+	issueID, err := ParseIssueNumber(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	issue, err := client.GetIssueData(repositoryID, issueID)
+	if err != nil {
+		return err
+	}
+
+	dependencies, err := client.GetDependencies(repositoryID)
+	switch {
+	case errors.Is(err, zenhub.ErrUnsupportedByAPI):
+		logrus.WithField("api", client.API()).Warn("Dependencies aren't available from this API, leaving them out")
+	case err != nil:
+		return err
+	}
+
+	info := IssueInfo{
+		IssueNumber:  issueID,
+		IsEpic:       issue.IsEpic,
+		PipelineID:   issue.Pipeline.PipelineID,
+		PipelineName: issue.Pipeline.Name,
+		BlockedBy:    []zenhub.DependencyIssue{},
+		Blocking:     []zenhub.DependencyIssue{},
+	}
+	if issue.Estimate != nil {
+		info.Estimate = &issue.Estimate.Value
+	}
+	for _, dependency := range dependencies {
+		if dependency.Blocked.RepositoryID == repositoryID && dependency.Blocked.IssueNumber == issueID {
+			info.BlockedBy = append(info.BlockedBy, dependency.Blocking)
+		}
+		if dependency.Blocking.RepositoryID == repositoryID && dependency.Blocking.IssueNumber == issueID {
+			info.Blocking = append(info.Blocking, dependency.Blocked)
+		}
+	}
+
+	if IsStructuredOutput(ctx) {
+		info.Resolved = ResolvedIDs{
+			WorkspaceID:  issue.Pipeline.WorkspaceID,
+			RepositoryID: repositoryID,
+			PipelineID:   issue.Pipeline.PipelineID,
+		}
+		return PrintStructured(info)
+	}
+
+	fmt.Printf("Issue:      %d\n", info.IssueNumber)
+	fmt.Printf("Pipeline:   %s (%s)\n", info.PipelineName, info.PipelineID)
+	if info.Estimate == nil {
+		fmt.Println("Estimate:   none")
+	} else {
+		fmt.Printf("Estimate:   %d\n", *info.Estimate)
+	}
+	fmt.Printf("Epic:       %t\n", info.IsEpic)
+	fmt.Printf("Blocked by: %s\n", formatDependencyIssues(info.BlockedBy, repositoryID))
+	fmt.Printf("Blocking:   %s\n", formatDependencyIssues(info.Blocking, repositoryID))
+
+	return nil
+}
+
+// formatDependencyIssues formats issues as a comma separated list, prefixing
+// issues from other repositories with their repository ID.
+func formatDependencyIssues(issues []zenhub.DependencyIssue, repositoryID uint) string {
+	if len(issues) == 0 {
+		return "none"
+	}
+	formatted := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		if issue.RepositoryID == repositoryID {
+			formatted = append(formatted, fmt.Sprintf("#%d", issue.IssueNumber))
+		} else {
+			formatted = append(formatted, fmt.Sprintf("%d#%d", issue.RepositoryID, issue.IssueNumber))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
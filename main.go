@@ -1,16 +1,31 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unicode"
 
 	dotenv "github.com/joho/godotenv"
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/nick96/zh/version"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 )
@@ -19,15 +34,15 @@ var (
 	// DefaultBaseURL is the base URL to build API endpoint URLs from.
 	//
 	// This can be configured via the command line .
-	DefaultBaseURL string = "https://api.zenhub.com"
+	DefaultBaseURL string = zenhub.DefaultBaseURL
 
 	// AuthenticationHeader is the header used to put the authentication
-	// token in.
-	AuthenticationHeader string = "X-Authentication-Token"
+	// token in. It is set by the `auth-header` flag.
+	AuthenticationHeader string = zenhub.DefaultAuthenticationHeader
 
 	// ZenHubTokenEnvVar is the environment variable to retrieve ZenHub
 	// token from.
-	ZenHubTokenEnvVar string = "ZENHUB_TOKEN"
+	ZenHubTokenEnvVar string = zenhub.TokenEnvVar
 
 	// ZenHubWorkspaceIDEnvVar is the environment variable to set the
 	// default ZenHub workspace.
@@ -37,230 +52,1707 @@ var (
 	// default ZenHub repository.
 	ZenHubRepositoryIDEnvVar string = "ZENHUB_REPOSITORY_ID"
 
+	// ZenHubTokenFileEnvVar is the environment variable to set the default
+	// file to read the ZenHub token from.
+	ZenHubTokenFileEnvVar string = "ZENHUB_TOKEN_FILE"
+
+	// ZenHubAuthHeaderEnvVar is the environment variable to set the default
+	// header the authentication token is put in.
+	ZenHubAuthHeaderEnvVar string = "ZENHUB_AUTH_HEADER"
+
+	// ZenHubAPIEnvVar is the environment variable to set the default API
+	// to send requests to.
+	ZenHubAPIEnvVar string = "ZENHUB_API"
+
+	// ZenHubBaseURLEnvVar is the environment variable to set the default
+	// base URL, e.g. for ZenHub Enterprise.
+	ZenHubBaseURLEnvVar string = "ZENHUB_BASE_URL"
+
+	// ZenHubConfigEnvVar is the environment variable to set the default
+	// config file.
+	ZenHubConfigEnvVar string = "ZENHUB_CONFIG"
+
+	// ZenHubProfileEnvVar is the environment variable to set the default
+	// config file profile.
+	ZenHubProfileEnvVar string = "ZENHUB_PROFILE"
+
 	// ZenHubLogLevelEnvVar is the environment variable to set the log
 	// level.
 	ZenHubLogLevelEnvVar string = "ZENHUB_LOG_LEVEL"
+
+	// ZenHubLogFormatEnvVar is the environment variable to set the default
+	// log format.
+	ZenHubLogFormatEnvVar string = "ZENHUB_LOG_FORMAT"
+
+	// DefaultTimeout is the default time each request to the API has to
+	// complete.
+	DefaultTimeout = 30 * time.Second
 )
 
-// MoveIssueRequest is the request body of a request to move an issue.
-type MoveIssueRequest struct {
-	PipelineID string `json:"pipeline_id"`
-	Position   string `json:"position"`
+// DefaultMoveConcurrency is the default number of issues moved in parallel
+// by issue mv.
+var DefaultMoveConcurrency uint = 4
+
+const (
+	// MoveStatusMoved is the status of an issue that was moved.
+	MoveStatusMoved string = "moved"
+
+	// MoveStatusSkipped is the status of an issue that was already in the
+	// target pipeline and left alone.
+	MoveStatusSkipped string = "skipped"
+
+	// MoveStatusFailed is the status of an issue that failed to move.
+	MoveStatusFailed string = "failed"
+
+	// MoveStatusCancelled is the status of an issue that wasn't moved
+	// because the command was interrupted first.
+	MoveStatusCancelled string = "cancelled"
+
+	// MoveStatusPlanned is the status of an issue that would have been
+	// moved if it wasn't a dry run.
+	MoveStatusPlanned string = "planned"
+)
+
+// MoveResult is the JSON output of moving an issue.
+type MoveResult struct {
+	IssueID      int                 `json:"issue_id"`
+	PipelineID   string              `json:"pipeline_id"`
+	Status       string              `json:"status"`
+	Reason       string              `json:"reason,omitempty"`
+	EpicID       int                 `json:"epic_id,omitempty"`
+	Rule         string              `json:"rule,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	StatusCode   int                 `json:"status_code,omitempty"`
+	Verification *VerificationReport `json:"verification,omitempty"`
+	Resolved     ResolvedIDs         `json:"resolved"`
+}
+
+// BatchMoveResult is the structured output of moving several issues with
+// `json-pretty`, a single object so the outcome of the whole batch can be
+// read at once. Planned moves count as succeeded and cancelled ones as
+// skipped.
+type BatchMoveResult struct {
+	Succeeded    []MoveResult        `json:"succeeded"`
+	Skipped      []MoveResult        `json:"skipped"`
+	Failed       []MoveFailure       `json:"failed"`
+	Milestones   []MilestoneResult   `json:"milestones,omitempty"`
+	Projects     []ProjectSyncResult `json:"github_project,omitempty"`
+	Verification *VerificationReport `json:"verification,omitempty"`
+}
+
+// MoveFailure is an issue in a `BatchMoveResult` that failed to move. Status
+// is the status code of the API's response, if it answered with an error.
+type MoveFailure struct {
+	IssueNumber int    `json:"issue_number"`
+	Status      int    `json:"status,omitempty"`
+	Message     string `json:"message"`
+}
+
+// NewBatchMoveResult groups the results of moving several issues by how they
+// turned out.
+func NewBatchMoveResult(results []MoveResult) BatchMoveResult {
+	batch := BatchMoveResult{
+		Succeeded: []MoveResult{},
+		Skipped:   []MoveResult{},
+		Failed:    []MoveFailure{},
+	}
+	for _, result := range results {
+		switch result.Status {
+		case MoveStatusMoved, MoveStatusPlanned:
+			batch.Succeeded = append(batch.Succeeded, result)
+		case MoveStatusSkipped, MoveStatusCancelled:
+			batch.Skipped = append(batch.Skipped, result)
+		case MoveStatusFailed:
+			batch.Failed = append(batch.Failed, MoveFailure{
+				IssueNumber: result.IssueID,
+				Status:      result.StatusCode,
+				Message:     result.Error,
+			})
+		}
+	}
+	return batch
+}
+
+// GetZenHubToken gets the ZenHub token and checks it looks like a token.
+//
+// Order of precedence is:
+//
+// 1. ZENHUB_TOKEN environment variable
+// 2. The contents of `tokenFile`, if it is set
+// 3. `token` in the config file
+func GetZenHubToken(tokenFile string) (string, error) {
+	token, source, err := findZenHubToken(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateToken(token, source); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findZenHubToken returns the ZenHub token, in the order of precedence of
+// `GetZenHubToken`, and a description of where it came from.
+func findZenHubToken(tokenFile string) (string, string, error) {
+	envVar := strings.TrimSpace(os.Getenv(ZenHubTokenEnvVar))
+	if envVar != "" {
+		return envVar, "environment variable " + ZenHubTokenEnvVar, nil
+	}
+	if tokenFile != "" {
+		contents, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read token file %s: %w", tokenFile, err)
+		}
+		token := strings.TrimSpace(string(contents))
+		if token == "" {
+			return "", "", fmt.Errorf("token file %s is empty", tokenFile)
+		}
+		return token, "token file " + tokenFile, nil
+	}
+	if token := strings.TrimSpace(fileConfig.Token); token != "" {
+		return token, "token in the config file", nil
+	}
+	return "", "", fmt.Errorf("expected environment variable %s, a token file or token in the config file", ZenHubTokenEnvVar)
+}
+
+// MinTokenLength is the length below which a token is assumed to have been
+// cut off when it was copied.
+const MinTokenLength = 20
+
+// tokenPattern matches the characters ZenHub tokens are made of.
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// ValidateToken checks the token from the given source looks like a ZenHub
+// token, to catch garbled values before they are sent to the API.
+//
+// Only characters that can't be part of any token, such as whitespace or
+// quotes left over from pasting, are an error. A token that is merely
+// shorter than expected or has unusual characters is logged as a warning,
+// so a change to ZenHub's token format doesn't lock anyone out.
+func ValidateToken(token, source string) error {
+	for _, r := range token {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == '"' || r == '\'' || r == '`' {
+			return fmt.Errorf("the ZenHub token from %s looks malformed, it contains %q. Check it was copied correctly, without quotes", source, r)
+		}
+	}
+
+	if len(token) < MinTokenLength || !tokenPattern.MatchString(token) {
+		logrus.WithField("source", source).Warn("The ZenHub token doesn't look like a ZenHub token, it may have been copied incorrectly")
+	}
+	return nil
+}
+
+// NewTransport creates the transport requests to ZenHub are sent through,
+// configured by the connection pool and retry flags.
+//
+// When the `record` or `replay` flags are set, requests go through a
+// `FixtureTransport` instead of straight to the network. When the
+// `debug-http` flag is set, every attempt at a request is logged by a
+// `DebugTransport`.
+func NewTransport(ctx *cli.Context) (http.RoundTripper, error) {
+	// The timeout applies to each attempt at a request as it is sent over
+	// the network, not to waits for the rate limit or before a retry.
+	var transport http.RoundTripper = zenhub.NewTimeoutTransport(zenhub.NewHTTPTransport(ctx.Uint("max-idle-conns"), ctx.Uint("max-conns-per-host")))
+
+	record, replay := ctx.String("record"), ctx.String("replay")
+	switch {
+	case record != "" && replay != "":
+		return nil, fmt.Errorf("only one of record and replay can be set")
+	case record != "":
+		transport = &FixtureTransport{transport: transport, dir: record}
+	case replay != "":
+		transport = &FixtureTransport{transport: transport, dir: replay, replay: true}
+	}
+
+	if ctx.Bool("debug-http") {
+		transport = &DebugTransport{transport: transport}
+	}
+
+	transport = zenhub.NewRetryTransport(transport, ctx.Uint("max-retries"), ctx.Duration("retry-base-delay"))
+
+	if ctx.Bool("dry-run") {
+		transport = &DryRunTransport{transport: transport}
+	}
+
+	return transport, nil
+}
+
+// NewClientFromContext creates the ZenHub client used by commands, talking to
+// the API at the `base-url` flag with the token from `GetZenHubToken`.
+// Requests go to the API chosen by `ResolveAPI`, are made in the command's
+// context and are limited by the `timeout` flag.
+func NewClientFromContext(ctx *cli.Context) (*zenhub.Client, error) {
+	token, err := GetZenHubToken(ctx.String("token-file"))
+	if err != nil {
+		return nil, err
+	}
+	redactionHook.AddSecret(token)
+
+	api, err := ResolveAPI(ctx.String("api"), token)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := NewTransport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := zenhub.NewClient(ctx.String("base-url"), token).
+		WithAPI(api).
+		WithTransport(transport).
+		WithAuthenticationHeader(AuthenticationHeader).
+		WithContext(ctx.Context).
+		WithTimeout(ctx.Duration("timeout"))
+	return client, nil
+}
+
+// APIAuto is the `api` flag value that picks the API from the token with
+// `zenhub.DetectAPI`.
+const APIAuto string = "auto"
+
+// ResolveAPI returns the API selected by the given `api` flag value,
+// detecting it from the token if the value is `APIAuto`.
+func ResolveAPI(api, token string) (zenhub.API, error) {
+	switch api {
+	case APIAuto:
+		detected := zenhub.DetectAPI(token)
+		logrus.WithField("api", detected).Debug("Detected API from the token")
+		return detected, nil
+	case string(zenhub.APIREST), string(zenhub.APIGraphQL):
+		return zenhub.API(api), nil
+	default:
+		return "", fmt.Errorf("invalid api value of %s, expected one of %s, %s or %s", api, APIAuto, zenhub.APIREST, zenhub.APIGraphQL)
+	}
+}
+
+// RequireGraphQL returns an error if the client doesn't send its requests to
+// the GraphQL API, which the given command is only available through.
+// Commands check it before prompting or sending anything, so they fail before
+// asking the user to confirm a change that can't be made.
+func RequireGraphQL(client *zenhub.Client, command string) error {
+	if client.API() != zenhub.APIGraphQL {
+		return fmt.Errorf("%s requires a GraphQL API key (zh_…), pass --api %s", command, zenhub.APIGraphQL)
+	}
+	return nil
+}
+
+// NormalizeBaseURL validates the given base URL and strips any surrounding
+// whitespace and trailing slashes so endpoint paths can be appended to it.
+func NormalizeBaseURL(baseURL string) (string, error) {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %s: %w", baseURL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid base URL %s: expected an http or https URL such as %s", baseURL, DefaultBaseURL)
+	}
+	return baseURL, nil
+}
+
+// ValidateHeaderName checks the given header name, stripped of surrounding
+// whitespace, is a valid HTTP header name and returns it.
+func ValidateHeaderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("invalid auth-header value, expected a header name such as X-Authentication-Token")
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return "", fmt.Errorf("invalid auth-header value of %s, header names can't contain %q", name, r)
+		}
+	}
+	return name, nil
+}
+
+// MoveIssueCommand moves issues between pipelines.
+//
+// All but the last argument are the issues to move and the last is the
+// pipeline to move them to, by ID, by a name that is fuzzy matched against
+// the board's pipelines, or by an alias from the config file's
+// `pipeline_aliases` for either. An issue argument of `-` reads the issues from
+// stdin instead, one per line. When moving several issues, a failed move
+// doesn't stop the rest. The failures are summarised at the end instead.
+//
+// With the `rules` flag every argument is an issue, and each is moved to the
+// pipeline of the first rule in the rules file it matches on GitHub instead.
+func MoveIssueCommand(ctx *cli.Context) error {
+	rulesPath := strings.TrimSpace(ctx.String("rules"))
+	args := ctx.Args().Slice()
+	if rulesPath != "" {
+		if len(args) == 0 {
+			return fmt.Errorf("expected at least one argument, the issue numbers. Received 0")
+		}
+		if ctx.Bool("create-pipeline") {
+			return fmt.Errorf("create-pipeline can't be used with rules, whose pipelines must exist")
+		}
+		args = append(args, "")
+	} else if len(args) < 2 {
+		return fmt.Errorf("expected at least two arguments, the issue numbers and the pipeline. Received %d", len(args))
+	}
+
+	issueArgs := make([]string, 0, len(args)-1)
+	readStdin := false
+	for _, arg := range args[:len(args)-1] {
+		if arg != "-" {
+			issueArgs = append(issueArgs, arg)
+			continue
+		}
+		if readStdin {
+			continue
+		}
+		readStdin = true
+		if ctx.Bool("create-pipeline") && !ctx.Bool("yes") {
+			return fmt.Errorf("create-pipeline needs yes to be set when reading issues from stdin, as stdin can't also answer the confirmation")
+		}
+		stdinArgs, err := ReadIssueArgs(os.Stdin)
+		if err != nil {
+			return err
+		}
+		issueArgs = append(issueArgs, stdinArgs...)
+	}
+	if len(issueArgs) == 0 {
+		return fmt.Errorf("no issues to move were given")
+	}
+
+	// Issues given by URL name their repository, which must be the same
+	// for every issue as they are all moved on one board.
+	issueIDs := make([]int, 0, len(issueArgs))
+	repository := ""
+	for _, arg := range issueArgs {
+		reference, err := ParseIssueReference(arg)
+		if err != nil {
+			return err
+		}
+		if reference.Repository != "" {
+			if repository != "" && !strings.EqualFold(repository, reference.Repository) {
+				return fmt.Errorf("issues are in different repositories, %s and %s", repository, reference.Repository)
+			}
+			repository = reference.Repository
+		}
+		issueIDs = append(issueIDs, reference.IssueNumber)
+	}
+
+	var rules *MoveRules
+	if rulesPath != "" {
+		var err error
+		if rules, err = LoadMoveRules(rulesPath); err != nil {
+			return err
+		}
+	}
+
+	// The pipeline may be an alias from the config file for a pipeline name
+	// or ID.
+	pipelineID := args[len(args)-1]
+	if pipeline, ok := fileConfig.PipelineAlias(pipelineID); ok {
+		logrus.WithFields(logrus.Fields{
+			"alias":    pipelineID,
+			"pipeline": pipeline,
+		}).Debug("Resolved pipeline alias")
+		pipelineID = pipeline
+	}
+
+	// Positions counting back from the end of the pipeline are resolved
+	// against the board for each issue.
+	position := ctx.String("position")
+	relativePosition := zenhub.IsRelativePosition(position)
+	if !relativePosition {
+		if err := zenhub.ValidatePosition(position); err != nil {
+			return err
+		}
+	}
+
+	onConflict := ctx.String("on-conflict")
+	if err := ValidateOnConflict(onConflict); err != nil {
+		return err
+	}
+
+	concurrency := ctx.Uint("concurrency")
+	if concurrency == 0 {
+		return fmt.Errorf("invalid concurrency value of %d", concurrency)
+	}
+	batchDelay := ctx.Duration("batch-delay")
+	if batchDelay < 0 {
+		return fmt.Errorf("invalid batch-delay value of %s", batchDelay)
+	}
+	if batchDelay > 0 && ctx.Uint("batch-size") == 0 {
+		return fmt.Errorf("batch-delay needs batch-size to be set")
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.Bool("create-pipeline") {
+		if err := RequireGraphQL(client, "create-pipeline"); err != nil {
+			return err
+		}
+	}
+	if ctx.Bool("json-pretty") && ctx.String("output") != OutputJSON {
+		return fmt.Errorf("json-pretty needs output to be %s", OutputJSON)
+	}
+	if ctx.Bool("print-curl") && client.API() != zenhub.APIREST {
+		return fmt.Errorf("print-curl is only supported with the %s API", zenhub.APIREST)
+	}
+
+	repositoryID, err := resolveMoveRepositoryID(ctx, repository)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	// Closed issues are checked for when GitHub can be asked about every
+	// issue, which needs a token to not hit GitHub's rate limit for
+	// anonymous requests.
+	var github *GitHubClient
+	assignee := strings.TrimSpace(ctx.String("assignee"))
+	if ctx.Bool("skip-closed") || assignee != "" || rules != nil || strings.TrimSpace(os.Getenv(GitHubTokenEnvVar)) != "" {
+		github, err = NewGitHubClientFromContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	assignee, err = ResolveAssignee(github, assignee)
+	if err != nil {
+		return err
+	}
+
+	milestones, err := NewMilestoneSetterFromContext(ctx, repositoryID)
+	if err != nil {
+		return err
+	}
+	projects, err := NewProjectSyncerFromContext(ctx, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	mover := &IssueMover{
+		ctx:              ctx,
+		client:           client,
+		github:           github,
+		workspaceID:      workspaceID,
+		repositoryID:     repositoryID,
+		pipelineID:       pipelineID,
+		position:         position,
+		onConflict:       onConflict,
+		assignee:         assignee,
+		rules:            rules,
+		wipLimit:         ctx.Uint("wip-limit"),
+		wipEstimateLimit: ctx.Uint("wip-estimate-limit"),
+		concurrency:      concurrency,
+		failFast:         ctx.Bool("fail-fast"),
+		batchSize:        ctx.Uint("batch-size"),
+		batchDelay:       batchDelay,
+	}
+
+	// A pipeline given by name, or by a rule, is resolved from the board,
+	// which also has the names of pipelines to sync to a GitHub project.
+	createPipeline := ctx.Bool("create-pipeline")
+	byName := rules == nil && !zenhub.LooksLikePipelineID(pipelineID)
+	if createPipeline || byName || rules != nil || projects != nil || relativePosition || mover.wipLimit > 0 || mover.wipEstimateLimit > 0 || onConflict != OnConflictMove {
+		board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+		if err != nil {
+			return err
+		}
+		mover.index = zenhub.NewBoardIndex(board)
+
+		switch {
+		case rules != nil:
+			err = rules.Resolve(mover.index, github)
+		case createPipeline:
+			mover.pipelineID, err = EnsurePipeline(ctx, client, mover.index, workspaceID, pipelineID)
+		case byName:
+			mover.pipelineID, err = MatchPipelineArg(mover.index, pipelineID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// With structured output each result is printed as a line of JSON or a
+	// YAML document, so nothing else is printed along the way. Several
+	// issues' results are streamed as each move finishes, unless
+	// `json-pretty` buffers them into one `BatchMoveResult`.
+	structuredOutput := IsStructuredOutput(ctx)
+	idOnly := ctx.Bool("output-id-only")
+	single := len(issueIDs) == 1
+	stream := structuredOutput && !single && !ctx.Bool("json-pretty")
+
+	var done func(j int, result MoveResult)
+	if stream {
+		var printMu sync.Mutex
+		done = func(j int, result MoveResult) {
+			printMu.Lock()
+			defer printMu.Unlock()
+			if err := PrintStructured(result); err != nil {
+				logrus.WithField("error", err).Error("Failed to print move result")
+			}
+		}
+	}
+	results, errs := mover.MoveAll(issueIDs, done)
+
+	if err := mover.saveUndoRecord(issueIDs); err != nil {
+		logrus.WithField("error", err).Warn("Failed to record the move, it can't be undone")
+	}
+
+	if single && errs[0] != nil {
+		return errs[0]
+	}
+
+	var failed []int
+	for j, err := range errs {
+		if err != nil {
+			failed = append(failed, issueIDs[j])
+		}
+	}
+	sort.Ints(failed)
+
+	// Milestones are only set on the issues that were moved, and failing to
+	// set one doesn't undo the move.
+	var milestoneResults []MilestoneResult
+	milestonesFailed := 0
+	if milestones != nil {
+		for _, result := range results {
+			if result.Status != MoveStatusMoved && result.Status != MoveStatusPlanned {
+				continue
+			}
+			milestoneResult := milestones.Set(result.IssueID)
+			if milestoneResult.Status == MilestoneStatusFailed {
+				milestonesFailed++
+			}
+			milestoneResults = append(milestoneResults, milestoneResult)
+		}
+	}
+
+	// Likewise for mirroring the moves to a GitHub project.
+	var projectResults []ProjectSyncResult
+	projectsFailed := 0
+	if projects != nil {
+		for _, result := range results {
+			if result.Status != MoveStatusMoved && result.Status != MoveStatusPlanned {
+				continue
+			}
+			pipeline := mover.index.Pipeline(result.PipelineID)
+			if pipeline == nil {
+				pipeline = &zenhub.Pipeline{ID: result.PipelineID, Name: result.PipelineID}
+			}
+			projectResult := projects.Sync(result.IssueID, pipeline)
+			if projectResult.Status == ProjectSyncStatusFailed {
+				projectsFailed++
+			}
+			projectResults = append(projectResults, projectResult)
+		}
+	}
+
+	// Issue numbers printed with `output-id-only` are for other commands to
+	// consume, so they are printed even when quiet.
+	quiet := IsQuiet(ctx)
+	if !structuredOutput && (idOnly || !quiet) {
+		for _, result := range results {
+			PrintMoveResult(result, idOnly)
+		}
+	}
+	if !structuredOutput && !idOnly && !quiet {
+		for _, result := range milestoneResults {
+			PrintMilestoneResult(result)
+		}
+		for _, result := range projectResults {
+			PrintProjectSyncResult(result)
+		}
+	}
+
+	var report *VerificationReport
+	interrupted := ctx.Err() != nil
+	if ctx.Bool("verify") && ctx.Bool("dry-run") {
+		logrus.Warn("Not verifying moves as this is a dry run")
+	} else if ctx.Bool("verify") && !interrupted && len(failed) < len(results) {
+		board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+		if err != nil {
+			return fmt.Errorf("failed to verify move: %w", err)
+		}
+		var moves []ExpectedMove
+		for _, result := range results {
+			if result.Status != MoveStatusFailed {
+				moves = append(moves, ExpectedMove{IssueNumber: result.IssueID, PipelineID: result.PipelineID})
+			}
+		}
+		verification := VerifyMoves(zenhub.NewBoardIndex(board), moves)
+		report = &verification
+	}
+
+	// Unless results are streamed, the verification is kept in the
+	// structured result so there is only one object to parse.
+	var verification *VerificationReport
+	if structuredOutput && !stream {
+		verification, report = report, nil
+	}
+	switch {
+	case structuredOutput && single:
+		results[0].Verification = verification
+		if err := PrintStructured(results[0]); err != nil {
+			return err
+		}
+		for _, result := range milestoneResults {
+			if err := PrintStructured(result); err != nil {
+				return err
+			}
+		}
+		for _, result := range projectResults {
+			if err := PrintStructured(result); err != nil {
+				return err
+			}
+		}
+	case stream:
+		for _, result := range milestoneResults {
+			if err := PrintStructured(result); err != nil {
+				return err
+			}
+		}
+		for _, result := range projectResults {
+			if err := PrintStructured(result); err != nil {
+				return err
+			}
+		}
+	case structuredOutput:
+		batch := NewBatchMoveResult(results)
+		batch.Milestones = milestoneResults
+		batch.Projects = projectResults
+		batch.Verification = verification
+		if err := PrintJSONIndented(batch); err != nil {
+			return err
+		}
+	}
+
+	moved, planned, skipped, cancelled := 0, 0, 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case MoveStatusMoved:
+			moved++
+		case MoveStatusPlanned:
+			planned++
+		case MoveStatusSkipped:
+			skipped++
+		case MoveStatusCancelled:
+			cancelled++
+		}
+	}
+	if !structuredOutput && !single && !idOnly && !quiet {
+		target := "pipeline " + mover.pipelineID
+		if rules != nil {
+			target = "the pipelines of their rules"
+		}
+		if ctx.Bool("dry-run") {
+			fmt.Printf("Would move %d issues to %s, skipped %d and failed to plan %d\n", planned, target, skipped, len(failed))
+		} else {
+			fmt.Printf("Moved %d issues to %s, skipped %d and failed to move %d\n", moved, target, skipped, len(failed))
+		}
+		if cancelled > 0 && interrupted {
+			fmt.Printf("Interrupted before moving %d issues\n", cancelled)
+		} else if cancelled > 0 {
+			fmt.Printf("Stopped after the first failure, before moving %d issues\n", cancelled)
+		}
+	}
+
+	var verifyErr error
+	if report != nil {
+		verifyErr = PrintVerificationReport(ctx, *report)
+	} else if verification != nil {
+		verifyErr = verification.Err()
+	}
+
+	err = verifyErr
+	if interrupted {
+		err = fmt.Errorf("interrupted after moving %d of %d issues: %w", moved, len(issueIDs), ctx.Err())
+	} else if len(failed) > 0 {
+		numbers := make([]string, 0, len(failed))
+		for _, issueID := range failed {
+			numbers = append(numbers, strconv.Itoa(issueID))
+		}
+		err = fmt.Errorf("failed to move %d of %d issues: %s", len(failed), len(issueIDs), strings.Join(numbers, ", "))
+	} else if milestonesFailed > 0 {
+		err = fmt.Errorf("failed to set milestone on %d of %d moved issues", milestonesFailed, len(milestoneResults))
+	} else if projectsFailed > 0 {
+		err = fmt.Errorf("failed to sync %d of %d moved issues to GitHub project %s", projectsFailed, len(projectResults), projects.name)
+	}
+
+	// The results already describe what went wrong, so the error only sets
+	// the exit code.
+	if err != nil && structuredOutput && !single {
+		return &ReportedError{Err: err}
+	}
+	return err
+}
+
+// ReadIssueArgs reads issue arguments, as accepted by `ParseIssueReference`,
+// one per line. Blank lines and `#` comments are skipped, although a line
+// such as `#42` is read as an issue number rather than a comment.
+func ReadIssueArgs(r io.Reader) ([]string, error) {
+	var args []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if _, err := ParseIssueNumber(line); err != nil {
+				continue
+			}
+		}
+		args = append(args, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read issues from stdin: %w", err)
+	}
+	return args, nil
+}
+
+// resolveMoveRepositoryID returns the ID of the repository of the issues
+// being moved: the repository from their URLs if they were given by URL,
+// otherwise the one from the flags.
+//
+// Only a repository given explicitly with `repository-id` or `repository`
+// has to agree with the URLs. Defaults from the environment or config file
+// are overridden by them.
+func resolveMoveRepositoryID(ctx *cli.Context, repository string) (uint, error) {
+	if repository == "" {
+		return ResolveRepositoryID(ctx)
+	}
+
+	if fullName := strings.TrimSpace(ctx.String("repository")); ctx.IsSet("repository") && !strings.EqualFold(fullName, repository) {
+		return 0, fmt.Errorf("issues are in repository %s but repo is %s", repository, fullName)
+	}
+
+	client, err := NewGitHubClientFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	repositoryID, err := client.GetRepositoryID(repository)
+	if err != nil {
+		return 0, err
+	}
+
+	// A cached ID that disagrees may be stale, so it is looked up again
+	// before giving up.
+	flagID := ctx.Uint("repository-id")
+	if ctx.IsSet("repository-id") && flagID != repositoryID {
+		repositoryID, err = client.RefreshRepositoryID(repository)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if ctx.IsSet("repository-id") && flagID != repositoryID {
+		return 0, fmt.Errorf("issues are in repository %s (%d) but repository-id is %d", repository, repositoryID, flagID)
+	}
+	return repositoryID, nil
+}
+
+// IssueMover moves issues to a pipeline with the settings shared by every
+// move in an invocation of `issue mv`.
+type IssueMover struct {
+	ctx              *cli.Context
+	client           *zenhub.Client
+	github           *GitHubClient
+	workspaceID      string
+	repositoryID     uint
+	pipelineID       string
+	position         string
+	onConflict       string
+	assignee         string
+	wipLimit         uint
+	wipEstimateLimit uint
+
+	// rules, if set, route each issue to the pipeline of the first rule it
+	// matches instead of `pipelineID`.
+	rules *MoveRules
+
+	// concurrency is the number of issues `MoveAll` moves at once, and
+	// failFast whether it leaves the rest where they are after a failure.
+	concurrency uint
+	failFast    bool
+
+	// batchSize is the number of issues `MoveAll` moves before pausing for
+	// batchDelay, to pace large moves. 0 moves every issue in one batch.
+	batchSize  uint
+	batchDelay time.Duration
+
+	// index is the board before the moves, kept up to date as issues are
+	// moved. It is nil if no check needs the board.
+	index *zenhub.BoardIndex
+
+	// undo is where the moved issues were before they were moved, recorded
+	// so `issue undo` can put them back.
+	undo []UndoMove
+
+	// issues are the issues looked up on GitHub, so each is only looked up
+	// once however many checks need it.
+	issues map[int]*GitHubIssue
+
+	// mu guards `index`, `undo` and `issues` between concurrent moves.
+	mu sync.Mutex
+
+	// boardMu makes moves that depend on the board as the moves before them
+	// left it one at a time. Concurrent moves checked against WIP limits
+	// could otherwise each pass the check and together take the pipeline over
+	// its limit, and moves relative to the end of the pipeline could each
+	// count the same length.
+	boardMu sync.Mutex
+}
+
+// newResult returns the result of moving the given issue to the mover's
+// pipeline with the given status, along with the IDs the move resolved to.
+func (m *IssueMover) newResult(issueID int, status string) MoveResult {
+	return MoveResult{
+		IssueID:    issueID,
+		PipelineID: m.pipelineID,
+		Status:     status,
+		Resolved: ResolvedIDs{
+			WorkspaceID:  m.workspaceID,
+			RepositoryID: m.repositoryID,
+			PipelineID:   m.pipelineID,
+		},
+	}
+}
+
+// MoveAll moves the given issues, `concurrency` at a time, in batches of
+// `batchSize` with a pause of `batchDelay` after each. Results and errors are
+// returned in the order of the issues, regardless of the order the moves
+// finish in, so the output is the same from run to run. If `done` isn't nil,
+// it is called with each issue's index and result as its move finishes.
+//
+// Once interrupted, or after a failure with `failFast`, the remaining issues
+// are left where they are and reported as cancelled.
+func (m *IssueMover) MoveAll(issueIDs []int, done func(j int, result MoveResult)) ([]MoveResult, []error) {
+	results := make([]MoveResult, len(issueIDs))
+	errs := make([]error, len(issueIDs))
+	var stopped int32
+	cancelled := func() bool {
+		return m.ctx.Err() != nil || atomic.LoadInt32(&stopped) != 0
+	}
+
+	size := len(issueIDs)
+	if m.batchSize > 0 && int(m.batchSize) < size {
+		size = int(m.batchSize)
+	}
+	batches := (len(issueIDs) + size - 1) / size
+	for batch, start := 1, 0; start < len(issueIDs); batch, start = batch+1, start+size {
+		if start > 0 && m.batchDelay > 0 && !cancelled() {
+			timer := time.NewTimer(m.batchDelay)
+			select {
+			case <-timer.C:
+			case <-m.ctx.Done():
+				timer.Stop()
+			}
+		}
+		end := start + size
+		if end > len(issueIDs) {
+			end = len(issueIDs)
+		}
+
+		var wg sync.WaitGroup
+		work := make(chan int)
+		for i := uint(0); i < m.concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range work {
+					if cancelled() {
+						results[j] = m.newResult(issueIDs[j], MoveStatusCancelled)
+					} else if results[j], errs[j] = m.Move(issueIDs[j]); errs[j] != nil {
+						// A single issue's error is returned instead.
+						if len(issueIDs) > 1 {
+							logrus.WithFields(logrus.Fields{
+								"issue_id": issueIDs[j],
+								"error":    errs[j],
+							}).Error("Failed to move issue")
+						}
+						results[j] = failedResult(results[j], errs[j])
+						if m.failFast {
+							atomic.StoreInt32(&stopped, 1)
+						}
+					}
+					if done != nil {
+						done(j, results[j])
+					}
+				}
+			}()
+		}
+		for j := start; j < end; j++ {
+			work <- j
+		}
+		close(work)
+		wg.Wait()
+
+		if m.batchSize > 0 {
+			logrus.WithFields(logrus.Fields{
+				"batch":   batch,
+				"batches": batches,
+				"issues":  end,
+				"total":   len(issueIDs),
+			}).Info("Finished batch of moves")
+		}
+	}
+	return results, errs
+}
+
+// failedResult returns the result of the move having failed with the given
+// error, along with the status code of the API's response if it answered
+// with one.
+func failedResult(result MoveResult, err error) MoveResult {
+	result.Status = MoveStatusFailed
+	result.Error = err.Error()
+	var statusErr *zenhub.StatusError
+	if errors.As(err, &statusErr) {
+		result.StatusCode = statusErr.StatusCode
+	}
+	return result
+}
+
+// Move moves the given issue, checking it against the on-conflict setting and
+// WIP limits first if the board was fetched. It is safe to call from
+// several goroutines at once.
+//
+// Failing to attach the issue to an epic doesn't undo the move, so it is
+// logged rather than returned.
+func (m *IssueMover) Move(issueID int) (MoveResult, error) {
+	result := m.newResult(issueID, MoveStatusMoved)
+
+	pipelineID := m.pipelineID
+	if m.rules != nil {
+		issue, err := m.gitHubIssue(issueID)
+		if err != nil {
+			return result, fmt.Errorf("failed to look up issue %d to match it against the rules: %w", issueID, err)
+		}
+		rule := m.rules.Match(issue)
+		if rule == nil {
+			result.Status = MoveStatusSkipped
+			result.Reason = "it matches no rule"
+			return result, nil
+		}
+		pipelineID = rule.Pipeline
+		result.PipelineID, result.Resolved.PipelineID, result.Rule = pipelineID, pipelineID, rule.Name
+	}
+
+	if m.wipLimit > 0 || m.wipEstimateLimit > 0 || zenhub.IsRelativePosition(m.position) {
+		m.boardMu.Lock()
+		defer m.boardMu.Unlock()
+	}
+
+	if m.index != nil {
+		skip, err := m.check(issueID, pipelineID)
+		if err != nil {
+			return result, err
+		}
+		if skip {
+			result.Status = MoveStatusSkipped
+			return result, nil
+		}
+	}
+
+	if m.assignee != "" {
+		issue, err := m.gitHubIssue(issueID)
+		if err != nil {
+			return result, fmt.Errorf("failed to check issue %d is assigned to %s: %w", issueID, m.assignee, err)
+		}
+		if !issue.IsAssignedTo(m.assignee) {
+			result.Status = MoveStatusSkipped
+			result.Reason = fmt.Sprintf("it isn't assigned to %s", m.assignee)
+			return result, nil
+		}
+	}
+
+	if closed := m.isClosed(issueID); closed && m.ctx.Bool("skip-closed") {
+		result.Status = MoveStatusSkipped
+		result.Reason = "it is closed"
+		return result, nil
+	} else if closed {
+		logrus.WithField("issue_id", issueID).Warn("Moving closed issue, use skip-closed to leave closed issues where they are")
+	}
+
+	dryRun := m.ctx.Bool("dry-run")
+	var previous *UndoMove
+	if !dryRun {
+		location, err := m.previousLocation(issueID, pipelineID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"issue_id": issueID,
+				"error":    err,
+			}).Warn("Failed to get the issue's current pipeline, the move can't be undone")
+		} else {
+			previous = &location
+		}
+	}
+
+	position, err := m.resolvePosition(issueID, pipelineID)
+	if err != nil {
+		return result, err
+	}
+	request := zenhub.MoveIssueRequest{
+		PipelineID: pipelineID,
+		Position:   position,
+	}
+	if m.ctx.Bool("print-curl") {
+		url := m.client.MoveIssueURL(m.workspaceID, m.repositoryID, issueID)
+		body, err := json.Marshal(request)
+		if err != nil {
+			return result, fmt.Errorf("failed to convert move issue request %v to JSON: %w", request, err)
+		}
+		fmt.Fprintln(os.Stderr, CurlCommand(http.MethodPost, url, body))
+	}
+	if err := m.client.MoveIssue(m.workspaceID, m.repositoryID, issueID, request); err != nil {
+		if zenhub.HasStatusCode(err, 404) {
+			InvalidateRepositoryID(m.repositoryID)
+			InvalidateWorkspaceID(m.workspaceID)
+		}
+		return result, err
+	}
+	if dryRun {
+		result.Status = MoveStatusPlanned
+	}
+	m.mu.Lock()
+	if m.index != nil {
+		m.index.MoveIssue(issueID, pipelineID, position)
+	}
+	if previous != nil {
+		m.undo = append(m.undo, *previous)
+	}
+	m.mu.Unlock()
+
+	if epicID := m.ctx.Int("epic"); epicID != 0 {
+		request := zenhub.UpdateEpicIssuesRequest{
+			AddIssues: []zenhub.EpicIssue{{RepositoryID: m.repositoryID, IssueNumber: issueID}},
+		}
+		if err := m.client.UpdateEpicIssues(m.repositoryID, epicID, request); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"issue_id": issueID,
+				"epic_id":  epicID,
+				"error":    err,
+			}).Error("Failed to add issue to epic")
+		} else {
+			result.EpicID = epicID
+		}
+	}
+
+	return result, nil
+}
+
+// isClosed reports whether the issue is closed on GitHub. Issues are assumed
+// to be open if GitHub isn't being asked or can't say.
+func (m *IssueMover) isClosed(issueID int) bool {
+	if m.github == nil {
+		return false
+	}
+	issue, err := m.gitHubIssue(issueID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"issue_id": issueID,
+			"error":    err,
+		}).Warn("Failed to check whether the issue is closed, assuming it is open")
+		return false
+	}
+	return issue.State == GitHubIssueStateClosed
+}
+
+// gitHubIssue looks up the issue on GitHub, the first time it is asked for.
+func (m *IssueMover) gitHubIssue(issueID int) (*GitHubIssue, error) {
+	m.mu.Lock()
+	issue, ok := m.issues[issueID]
+	m.mu.Unlock()
+	if ok {
+		return issue, nil
+	}
+
+	issue, err := m.github.GetIssue(m.repositoryID, issueID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	if m.issues == nil {
+		m.issues = make(map[int]*GitHubIssue)
+	}
+	m.issues[issueID] = issue
+	m.mu.Unlock()
+	return issue, nil
 }
 
-// AuthenticationTransport is a custom transport that adds the ZenHub token to
-// the `AuthenticationHeader`.
-type AuthenticationTransport struct {
-	transport           http.RoundTripper
-	authenticationToken string
+// resolvePosition returns the position to move the issue to in the given
+// pipeline, converting a position relative to the end of the pipeline into
+// an index from the pipeline's length on the board.
+func (m *IssueMover) resolvePosition(issueID int, pipelineID string) (zenhub.MovePosition, error) {
+	if !zenhub.IsRelativePosition(m.position) {
+		return zenhub.MovePosition(m.position), nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	length := len(m.index.PipelineIssues(pipelineID))
+	if current, _ := m.index.Issue(issueID); current != nil && current.ID == pipelineID {
+		length--
+	}
+	position, err := zenhub.ResolveRelativePosition(m.position, length)
+	if err != nil {
+		return "", fmt.Errorf("failed to move issue %d: %w", issueID, err)
+	}
+	logrus.WithFields(logrus.Fields{
+		"issue_id": issueID,
+		"relative": m.position,
+		"position": position,
+	}).Debug("Resolved relative position")
+	return position, nil
 }
 
-// RoundTrip adds the `AuthenticationHeader` to the request and calls the
-// wrapped `transport`.
-func (t *AuthenticationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Add(AuthenticationHeader, t.authenticationToken)
-	return t.transport.RoundTrip(req)
+// check checks moving the issue to the given pipeline against the
+// on-conflict setting and WIP limits, returning whether it should be skipped.
+func (m *IssueMover) check(issueID int, pipelineID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, _ := m.index.Issue(issueID); current != nil && current.ID == pipelineID {
+		switch m.onConflict {
+		case OnConflictSkip:
+			return true, nil
+		case OnConflictError:
+			return false, fmt.Errorf("issue %d is already in pipeline %s", issueID, pipelineID)
+		}
+	}
+
+	if m.wipLimit > 0 || m.wipEstimateLimit > 0 {
+		if err := CheckWIPLimits(m.index, issueID, pipelineID, m.wipLimit, m.wipEstimateLimit); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
 }
 
-// GetZenHubToken gets the ZenHub token.
-//
-// Order of precedence is:
-//
-// 1. ZENHUB_TOKEN environment variable
-func GetZenHubToken() (string, error) {
-	envVar := strings.TrimSpace(os.Getenv(ZenHubTokenEnvVar))
-	if envVar != "" {
-		return envVar, nil
-	}
-	return "", fmt.Errorf("expected environment variable %s", ZenHubTokenEnvVar)
-}
-
-// ErrorFromStatusCode converts the given status code into a more informative
-// error message.
-func ErrorFromStatusCode(statusCode int) error {
-	switch statusCode {
-	case 401:
-		return fmt.Errorf("authentication token is not valid. Check that %s is set correctly", ZenHubTokenEnvVar)
-	case 403:
-		return fmt.Errorf("ZenHub API request limit reached. Please try again later")
-	case 404:
-		return fmt.Errorf("endpoint not found. This most likely is a bug in zh, please report it")
-	case 200:
+// PrintMoveResult prints the result of moving an issue as text. Scripts
+// chaining on the moved issues only want their numbers, so with `idOnly`
+// only the number of a moved issue is printed.
+func PrintMoveResult(result MoveResult, idOnly bool) {
+	switch {
+	case result.Status == MoveStatusMoved && idOnly:
+		fmt.Println(result.IssueID)
+	case result.Status == MoveStatusMoved:
+		// An empty message silences moved issues.
+		if message := MoveSuccessMessage(result.IssueID, result.PipelineID); message != "" {
+			fmt.Println(message)
+		}
+		if result.Rule != "" {
+			fmt.Printf("Issue %d matched rule %s\n", result.IssueID, result.Rule)
+		}
+		if result.EpicID != 0 {
+			fmt.Printf("Successfully added issue %d to epic %d\n", result.IssueID, result.EpicID)
+		}
+	case result.Status == MoveStatusPlanned && idOnly:
+		fmt.Println(result.IssueID)
+	case result.Status == MoveStatusPlanned:
+		fmt.Printf("Would move issue %d to pipeline %s\n", result.IssueID, result.PipelineID)
+		if result.Rule != "" {
+			fmt.Printf("Issue %d matched rule %s\n", result.IssueID, result.Rule)
+		}
+		if result.EpicID != 0 {
+			fmt.Printf("Would add issue %d to epic %d\n", result.IssueID, result.EpicID)
+		}
+	case result.Status == MoveStatusSkipped && !idOnly && result.Reason != "":
+		fmt.Printf("Skipped issue %d, %s\n", result.IssueID, result.Reason)
+	case result.Status == MoveStatusSkipped && !idOnly:
+		fmt.Printf("Skipped issue %d, it is already in pipeline %s\n", result.IssueID, result.PipelineID)
+	}
+}
+
+const (
+	// OnConflictMove moves an issue even if it is already in the target
+	// pipeline, reordering it within the pipeline.
+	OnConflictMove string = "move"
+
+	// OnConflictSkip leaves an issue that is already in the target pipeline
+	// where it is and reports it as skipped.
+	OnConflictSkip string = "skip"
+
+	// OnConflictError fails if an issue is already in the target pipeline.
+	OnConflictError string = "error"
+)
+
+// ValidateOnConflict checks the given value is a supported `on-conflict`
+// mode.
+func ValidateOnConflict(onConflict string) error {
+	switch onConflict {
+	case OnConflictMove, OnConflictSkip, OnConflictError:
 		return nil
 	default:
-		return fmt.Errorf("unknown status code %d. This most likely is a bug in zh, please report it", statusCode)
+		return fmt.Errorf("invalid on-conflict value of %s, expected one of %s, %s or %s",
+			onConflict, OnConflictMove, OnConflictSkip, OnConflictError)
 	}
 }
 
-// MoveIssueCommand moves issues between pipelines.
-func MoveIssueCommand(ctx *cli.Context) error {
-	if ctx.Args().Len() != 2 {
-		return fmt.Errorf("expected exactly two argument, the issue ID and the pipeline ID. Received %d", ctx.Args().Len())
+// MatchPipelineArg returns the ID of the pipeline the given pipeline argument
+// refers to, trying an exact name first, then an ID, then fuzzy matching the
+// name like `zenhub.BoardIndex.MatchPipelineID`.
+func MatchPipelineArg(index *zenhub.BoardIndex, target string) (string, error) {
+	pipelineID, err := index.ResolvePipelineName(target)
+	if err == nil {
+		logrus.WithFields(logrus.Fields{
+			"target":      target,
+			"pipeline_id": pipelineID,
+		}).Debug("Resolved pipeline by name")
+		return pipelineID, nil
 	}
-
-	issueID, err := strconv.Atoi(ctx.Args().First())
-	if err != nil {
-		return fmt.Errorf("expected issue ID to be an int, got %s", ctx.Args().First())
+	if !errors.Is(err, zenhub.ErrPipelineNotFound) {
+		return "", err
 	}
 
-	pipelineID := ctx.Args().Get(1)
+	if index.Pipeline(target) != nil {
+		logrus.WithField("pipeline_id", target).Debug("Resolved pipeline by ID")
+		return target, nil
+	}
+	return index.MatchPipelineID(target)
+}
 
-	token, err := GetZenHubToken()
-	if err != nil {
-		return err
+// EnsurePipeline returns the ID of the target pipeline, creating a pipeline
+// named `target` if no pipeline on the board has that ID or name. The user is
+// asked to confirm the creation unless the `yes` flag is set. Pipelines can
+// only be created through the GraphQL API, so other clients fail before the
+// user is asked.
+//
+// A created pipeline is added to the board so later checks can see it.
+func EnsurePipeline(ctx *cli.Context, client *zenhub.Client, index *zenhub.BoardIndex, workspaceID, target string) (string, error) {
+	pipelineID, err := index.ResolvePipelineID(target)
+	if !errors.Is(err, zenhub.ErrPipelineNotFound) {
+		return pipelineID, err
+	}
+	if err := RequireGraphQL(client, "create-pipeline"); err != nil {
+		return "", err
 	}
 
-	client := http.Client{
-		Transport: &AuthenticationTransport{
-			transport:           http.DefaultTransport,
-			authenticationToken: token,
-		},
+	if !ctx.Bool("yes") {
+		ok, err := Confirm(fmt.Sprintf("Pipeline %s does not exist. Create it in workspace %s?", target, workspaceID))
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("pipeline %s does not exist and was not created", target)
+		}
 	}
 
-	workspaceID := ctx.String("workspace-id")
-	if workspaceID == "" {
-		return fmt.Errorf("invalid workpace-id value of %s", workspaceID)
+	pipeline, err := client.CreatePipeline(workspaceID, target)
+	if err != nil {
+		return "", err
+	}
+	// Created pipelines are added to the end of the board.
+	index.AddPipeline(*pipeline, "bottom")
+	if !ctx.Bool("output-id-only") && !IsStructuredOutput(ctx) && !IsQuiet(ctx) {
+		fmt.Printf("Successfully created pipeline %s (%s)\n", pipeline.Name, pipeline.ID)
 	}
 
-	repositoryID := ctx.Uint("repository-id")
-	if repositoryID == 0 {
-		return fmt.Errorf("invalid repository-id value of %d", repositoryID)
+	return pipeline.ID, nil
+}
+
+// Confirm asks the user the given yes/no question on stderr, reading the
+// answer from stdin so the prompt stays out of the command's output. It
+// returns whether they answered yes.
+func Confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read answer: %w", err)
 	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
 
-	url := fmt.Sprintf("%s/p2/workspaces/%s/repositories/%d/issues/%d/moves",
-		ctx.String("base-url"),
-		workspaceID,
-		repositoryID,
-		issueID,
-	)
-	request := MoveIssueRequest{
-		PipelineID: pipelineID,
-		Position:   "bottom",
+// CheckWIPLimits checks that moving the given issue into the given pipeline
+// would not take the pipeline over its work in progress limits. A limit of 0
+// means there is no limit.
+func CheckWIPLimits(index *zenhub.BoardIndex, issueID int, pipelineID string, limit, estimateLimit uint) error {
+	target := index.Pipeline(pipelineID)
+	if target == nil {
+		return fmt.Errorf("pipeline %s not found on the board", pipelineID)
 	}
-	body, err := json.Marshal(request)
-	if err != nil {
-		return fmt.Errorf("failed to convert move issue request %v to JSON: %w", request, err)
+
+	count := len(target.Issues)
+	estimate := target.EstimateTotal()
+	newCount, newEstimate := count, estimate
+
+	current, issue := index.Issue(issueID)
+	if current == nil || current.ID != target.ID {
+		newCount++
+		if issue != nil && issue.Estimate != nil {
+			newEstimate += issue.Estimate.Value
+		}
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"url":  url,
-		"body": string(body),
-	}).Debug("Sending move issue request")
-	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to move issue between pipelines: %w", err)
-	}
+		"pipeline_id":  pipelineID,
+		"count":        count,
+		"new_count":    newCount,
+		"estimate":     estimate,
+		"new_estimate": newEstimate,
+	}).Debug("Checking WIP limits")
 
-	if err := ErrorFromStatusCode(resp.StatusCode); err != nil {
-		return fmt.Errorf("failed to move issue between pipelines: %w", err)
+	if limit > 0 && uint(newCount) > limit {
+		return fmt.Errorf("moving issue %d to pipeline %s would exceed the WIP limit of %d issues (currently %d, would be %d)",
+			issueID, target.Name, limit, count, newCount)
+	}
+	if estimateLimit > 0 && uint(newEstimate) > estimateLimit {
+		return fmt.Errorf("moving issue %d to pipeline %s would exceed the WIP estimate limit of %d points (currently %d, would be %d)",
+			issueID, target.Name, estimateLimit, estimate, newEstimate)
 	}
-
-	fmt.Printf("Successfully moved issue %d to pipeline %s\n", issueID, pipelineID)
 
 	return nil
 }
 
-
 // ListBoardCommand is the CLI command action for listing the contents
-// (pipelines) for board.
+// (pipelines) for board: the ID, name and issues of each pipeline, or the
+// board as returned by the API with structured output. The issues in each
+// pipeline are in board order unless sorted by the `sort` flag.
 func ListBoardCommand(ctx *cli.Context) error {
-	workspaceID := ctx.String("workspace-id")
-	if workspaceID == "" {
-		return fmt.Errorf("invalid workpace-id value of %s", workspaceID)
+	var key string
+	var descending bool
+	if value := ctx.String("sort"); value != "" {
+		var err error
+		if key, descending, err = ParseSort(value, BoardIssueSortKeys...); err != nil {
+			return err
+		}
 	}
 
-	repositoryID := ctx.Uint("repository-id")
-	if repositoryID == 0 {
-		return fmt.Errorf("invalid repository-id value of %d", repositoryID)
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
 	}
-	url := fmt.Sprintf(
-		"%s/p2/workspaces/%s/repositories/%d/board",
-		ctx.String("base-url"),
-		workspaceID,
-		repositoryID,
-	)
-	logrus.WithField("url", url).Debug("Sending list board request")
 
-	token, err := GetZenHubToken()
+	client, err := NewClientFromContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	client := http.Client{
-		Transport: &AuthenticationTransport{
-			transport:           http.DefaultTransport,
-			authenticationToken: token,
-		},
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
 	}
-	resp, err := client.Get(url)
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
 	if err != nil {
 		return fmt.Errorf("failed to list board: %w", err)
 	}
+	if key != "" {
+		SortBoardIssues(board, key, descending)
+	}
 
-	if err := ErrorFromStatusCode(resp.StatusCode); err != nil {
-		return fmt.Errorf("failed to list board: %w", err)
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(board)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read body of list board response")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, pipeline := range board.Pipelines {
+		issues := make([]string, 0, len(pipeline.Issues))
+		for _, issue := range pipeline.Issues {
+			issues = append(issues, fmt.Sprintf("#%d", issue.IssueNumber))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", pipeline.ID, pipeline.Name, strings.Join(issues, " "))
 	}
-	defer resp.Body.Close()
-	fmt.Println(string(body))
+	return tw.Flush()
+}
 
+// VersionCommand prints the version, git commit and build date of zh, so it
+// can be included in bug reports.
+func VersionCommand(ctx *cli.Context) error {
+	fmt.Printf("zh version %s\n", version.String())
 	return nil
 }
 
-func main() {
-	if err := dotenv.Load(); err != nil {
-		logrus.WithField("error", err).Warn("failed to load .env file in working directory")
+const (
+	// LogFormatText is the log format for human readable logs.
+	LogFormatText string = "text"
+
+	// LogFormatJSON is the log format for logs as one JSON object per line.
+	LogFormatJSON string = "json"
+)
+
+// SetLogFormat sets the formatter of log output to the given log format.
+func SetLogFormat(format string) error {
+	switch format {
+	case LogFormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	case LogFormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid log-format value of %s, expected one of %s or %s", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// SetLogLevelFromEnv sets the log level to the one given by
+// ZENHUB_LOG_LEVEL, if it is set. An invalid level is warned about and
+// ignored.
+func SetLogLevelFromEnv() {
+	level := os.Getenv(ZenHubLogLevelEnvVar)
+	if level == "" {
+		return
+	}
+	logrusLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"value":   level,
+			"env_var": ZenHubLogLevelEnvVar,
+		}).Warn("Invalid log level, expected one of trace, debug, info, warn, error, fatal or panic")
+		return
 	}
+	logrus.SetLevel(logrusLevel)
+}
 
-	if level := os.Getenv(ZenHubLogLevelEnvVar); level != "" {
-		logrusLevel, err := logrus.ParseLevel(level)
-		if err != nil {
-			logrus.WithField("value", level).Warn("Invalid logrus level '%s' specified by %s", level, ZenHubLogLevelEnvVar)
-		} else {
-			logrus.SetLevel(logrusLevel)
+// SetupLogFile directs log output to the file given by the `log-file` flag,
+// in addition to stderr unless `log-file-only` is set.
+func SetupLogFile(ctx *cli.Context) error {
+	path := ctx.String("log-file")
+	if path == "" {
+		if ctx.Bool("log-file-only") {
+			return fmt.Errorf("log-file-only requires log-file to be set")
 		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for log file %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+
+	if ctx.Bool("log-file-only") {
+		logrus.SetOutput(file)
+	} else {
+		logrus.SetOutput(io.MultiWriter(os.Stderr, file))
+	}
+
+	return nil
+}
+
+// NewSetEstimateCommand returns the command that sets the estimate of an
+// issue, with the given name.
+func NewSetEstimateCommand(name string) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     "Set the estimate of an issue",
+		ArgsUsage: "<issue-number> <estimate>",
+		Action:    SetEstimateCommand,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Set the estimate even if it isn't one of the workspace's estimate values.",
+			},
+		},
+	}
+}
+
+// NewApp creates the zh command line app. Defaults are read from the
+// environment when it is created and from the config file when it is run.
+func NewApp() *cli.App {
+	// Configuration errors are returned from `Before`, rather than being
+	// fatal here, so they are reported in the requested output format.
+	var configErr error
+
+	// `issue estimate` predates `estimate set` and is kept as a hidden alias
+	// of it for scripts that use it.
+	issueEstimate := NewSetEstimateCommand("estimate")
+	issueEstimate.Hidden = true
+
+	// Defaults are resolved in order of precedence: environment variable,
+	// then config file, then the built in default. Flags override them all.
+	// The config file is only read in `Before`, once the `config` flag is
+	// parsed, so the environment variables are the flags' defaults here.
+	defaultBaseURL := DefaultBaseURL
+	if baseURLEnv := strings.TrimSpace(os.Getenv(ZenHubBaseURLEnvVar)); baseURLEnv != "" {
+		defaultBaseURL = baseURLEnv
 	}
 
 	defaultWorkspaceID := os.Getenv(ZenHubWorkspaceIDEnvVar)
 
-	defaultRepositoryID := uint(0)
+	var defaultRepositoryID uint
 	if repoIDEnv := os.Getenv(ZenHubRepositoryIDEnvVar); strings.TrimSpace(repoIDEnv) != "" {
 		repoID, err := strconv.Atoi(repoIDEnv)
 		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"error": err,
-				"name":  ZenHubRepositoryIDEnvVar,
-				"value": repoIDEnv,
-			}).Fatal("invalid value for default repository ID")
+			configErr = fmt.Errorf("invalid value %s for default repository ID set by %s: %w", repoIDEnv, ZenHubRepositoryIDEnvVar, err)
 		}
 		defaultRepositoryID = uint(repoID)
 	}
 
-	app := cli.App{
-		Name:  "zh",
-		Usage: "Control ZenHub from the command line!",
+	app := &cli.App{
+		Name:                 "zh",
+		Usage:                "Control ZenHub from the command line!",
+		Version:              version.String(),
+		EnableBashCompletion: true,
+		Before: func(ctx *cli.Context) error {
+			if err := SetOutputFormat(ctx); err != nil {
+				return err
+			}
+			if err := SetLogFormat(ctx.String("log-format")); err != nil {
+				return err
+			}
+			if ctx.Bool("verbose") {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			if configErr != nil {
+				return configErr
+			}
+			if err := SetupConfig(ctx); err != nil {
+				// A broken config file is fixed with config edit, so it
+				// mustn't stop it from running.
+				if ctx.Args().First() != "config" || ctx.Args().Get(1) != "edit" {
+					return err
+				}
+				logrus.WithField("error", err).Warn("Failed to load config file")
+			}
+			baseURL, err := NormalizeBaseURL(ctx.String("base-url"))
+			if err != nil {
+				return err
+			}
+			if err := ctx.Set("base-url", baseURL); err != nil {
+				return err
+			}
+			header, err := ValidateHeaderName(ctx.String("auth-header"))
+			if err != nil {
+				return err
+			}
+			AuthenticationHeader = header
+			if _, err := ResolveAPI(ctx.String("api"), ""); err != nil {
+				return err
+			}
+			return SetupLogFile(ctx)
+		},
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "Config file to read defaults from. Defaults to $XDG_CONFIG_HOME/zh/config.yaml, which unlike a given file may be missing.",
+				EnvVars: []string{ZenHubConfigEnvVar},
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Config file profile to take the token, workspace, repository and base URL from. Defaults to profile in the config file. Flags and environment variables take precedence over it.",
+				EnvVars: []string{ZenHubProfileEnvVar},
+			},
 			&cli.StringFlag{
 				Name:  "base-url",
-				Value: DefaultBaseURL,
-				Usage: "Base URL to build API endpoints from.",
+				Value: defaultBaseURL,
+				Usage: fmt.Sprintf("Base URL to build API endpoints from. Defaults to %s if set, then base_url in the config file.", ZenHubBaseURLEnvVar),
 			},
 			&cli.StringFlag{
 				Name:    "workspace-id",
 				Aliases: []string{"w"},
-				Usage:   "ID of the target workspace.",
+				Usage:   fmt.Sprintf("ID of the target workspace. Defaults to %s if set, then workspace_id in the config file. Otherwise inferred from the repository if it belongs to only one workspace.", ZenHubWorkspaceIDEnvVar),
 				Value:   defaultWorkspaceID,
 			},
+			&cli.StringFlag{
+				Name:  "workspace",
+				Usage: "Name of the target workspace, looked up among the repository's workspaces and cached for later runs. --workspace-id takes precedence when both are given.",
+			},
 			&cli.UintFlag{
 				Name:    "repository-id",
 				Aliases: []string{"r"},
-				Usage:   "ID of the target repository.",
+				Usage:   fmt.Sprintf("ID of the target repository. Defaults to %s if set, then repository_id in the config file. Takes precedence over --repo when both are given. Prefer --repo, which doesn't need the ID.", ZenHubRepositoryIDEnvVar),
 				Value:   defaultRepositoryID,
 			},
+			&cli.StringFlag{
+				Name:    "repository",
+				Aliases: []string{"repo"},
+				Usage:   fmt.Sprintf("Target repository as owner/name, looked up on GitHub using %s if set and cached for later runs. Takes precedence over the default repository ID.", GitHubTokenEnvVar),
+			},
+			&cli.StringFlag{
+				Name:    "token-file",
+				Usage:   fmt.Sprintf("File to read the ZenHub token from when %s is not set.", ZenHubTokenEnvVar),
+				EnvVars: []string{ZenHubTokenFileEnvVar},
+			},
+			&cli.StringFlag{
+				Name:    "auth-header",
+				Value:   AuthenticationHeader,
+				Usage:   "Header to put the authentication token in, e.g. for a proxy in front of ZenHub.",
+				EnvVars: []string{ZenHubAuthHeaderEnvVar},
+			},
+			&cli.StringFlag{
+				Name:    "api",
+				Value:   APIAuto,
+				Usage:   fmt.Sprintf("ZenHub API to use, one of %s, %s or %s. %s picks %s for GraphQL API keys, which start with zh_, and %s otherwise.", APIAuto, zenhub.APIREST, zenhub.APIGraphQL, APIAuto, zenhub.APIGraphQL, zenhub.APIREST),
+				EnvVars: []string{ZenHubAPIEnvVar},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   fmt.Sprintf("Output format, one of %s, %s or %s.", OutputTable, OutputJSON, OutputYAML),
+				Value:   OutputTable,
+			},
+			&cli.StringFlag{
+				Name:  "log-file",
+				Usage: "Also write logs to the given file, creating parent directories as needed.",
+			},
+			&cli.BoolFlag{
+				Name:  "log-file-only",
+				Usage: "Only write logs to the file given by --log-file, not to stderr.",
+			},
+			&cli.BoolFlag{
+				Name:  "retry-on-truncation",
+				Usage: "Re-fetch the board if its response is cut off part way through.",
+			},
+			&cli.BoolFlag{
+				Name:  "debug-http",
+				Usage: "Log every request to and response from the API in full, with the token redacted.",
+			},
+			&cli.StringFlag{
+				Name:    "log-format",
+				Value:   LogFormatText,
+				Usage:   fmt.Sprintf("Format of log output, one of %s or %s.", LogFormatText, LogFormatJSON),
+				EnvVars: []string{ZenHubLogFormatEnvVar},
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Don't print messages reporting success. Errors, logs and structured output are unaffected.",
+			},
+			&cli.BoolFlag{
+				Name:  "verbose",
+				Usage: fmt.Sprintf("Log debug output, such as the rate limit after each request. Overrides %s.", ZenHubLogLevelEnvVar),
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Log the method, URL and body of requests that would change anything instead of sending them. Requests that only read are still sent.",
+			},
+			&cli.StringFlag{
+				Name:  "record",
+				Usage: "Record API responses as fixtures in the given directory.",
+			},
+			&cli.StringFlag{
+				Name:  "replay",
+				Usage: "Replay API responses from fixtures in the given directory instead of using the network.",
+			},
+			&cli.UintFlag{
+				Name:  "max-idle-conns",
+				Usage: "Maximum number of idle (keep-alive) connections to keep open.",
+				Value: zenhub.DefaultMaxIdleConns,
+			},
+			&cli.UintFlag{
+				Name:  "max-conns-per-host",
+				Usage: "Maximum number of connections to the ZenHub API. 0 means no limit.",
+				Value: zenhub.DefaultMaxConnsPerHost,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "How long each request to the API has to complete. 0 means no timeout.",
+				Value: DefaultTimeout,
+			},
+			&cli.UintFlag{
+				Name:  "max-retries",
+				Usage: "Number of times to retry a request that was rate limited or hit a server error. 0 disables retries.",
+				Value: zenhub.DefaultMaxRetries,
+			},
+			&cli.DurationFlag{
+				Name:  "retry-base-delay",
+				Usage: "Delay before the first retry, doubling with each retry after that. A Retry-After header from the API takes precedence.",
+				Value: zenhub.DefaultRetryBaseDelay,
+			},
 		},
 		Commands: []*cli.Command{
 			{
@@ -268,27 +1760,420 @@ func main() {
 				Usage: "Work with issues",
 				Subcommands: []*cli.Command{
 					{
-						Name:   "mv",
-						Usage:  "Move an issue between pipelines",
-						Action: MoveIssueCommand,
+						Name:         "mv",
+						Usage:        "Move issues between pipelines",
+						ArgsUsage:    "<issue-number|issue-url|->... <pipeline-id|pipeline-name>",
+						Action:       MoveIssueCommand,
+						BashComplete: CompletePipelineIDs,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "position",
+								Aliases: []string{"p"},
+								Usage:   "Where to put the issue in the pipeline: top, bottom or a zero-based index, e.g. 2 to make it the third issue. Negative indexes count back from the end, e.g. -1 for last and -2 for second to last.",
+								Value:   "bottom",
+							},
+							&cli.StringFlag{
+								Name:  "set-milestone",
+								Usage: fmt.Sprintf("Title of the GitHub milestone to set on the issues once they are moved, which needs %s to be set.", GitHubTokenEnvVar),
+							},
+							&cli.BoolFlag{
+								Name:  "create-milestone",
+								Usage: "Create the milestone given by --set-milestone if the repository doesn't have it.",
+							},
+							&cli.StringFlag{
+								Name:  "sync-github-project",
+								Usage: fmt.Sprintf("GitHub project, as owner/number or its URL, to set the status of the issues in once they are moved, mirroring their pipelines as mapped by github_project in the config file. Needs %s to be set.", GitHubTokenEnvVar),
+							},
+							&cli.StringFlag{
+								Name:  "assignee",
+								Usage: fmt.Sprintf("Only move the issues assigned to this GitHub user, leaving the rest where they are. Use %s for the user %s belongs to.", AssigneeMe, GitHubTokenEnvVar),
+							},
+							&cli.StringFlag{
+								Name:  "rules",
+								Usage: fmt.Sprintf("YAML file of rules routing issues to pipelines by their GitHub label or assignee, moving each issue to the pipeline of the first rule it matches. Every argument is then an issue. Issues are looked up on GitHub using %s if set.", GitHubTokenEnvVar),
+							},
+							&cli.BoolFlag{
+								Name:  "skip-closed",
+								Usage: fmt.Sprintf("Leave issues that are closed on GitHub where they are instead of warning and moving them. Closed issues are only looked for with this set or %s set.", GitHubTokenEnvVar),
+							},
+							&cli.UintFlag{
+								Name:  "wip-limit",
+								Usage: "Refuse the move if the target pipeline would hold more than this many issues. 0 means no limit.",
+							},
+							&cli.UintFlag{
+								Name:  "wip-estimate-limit",
+								Usage: "Refuse the move if the target pipeline's estimate total would exceed this many points. 0 means no limit.",
+							},
+							&cli.StringFlag{
+								Name: "on-conflict",
+								Usage: fmt.Sprintf("What to do if the issue is already in the target pipeline: "+
+									"%s reorders it within the pipeline, %s leaves it where it is, %s fails.",
+									OnConflictMove, OnConflictSkip, OnConflictError),
+								Value: OnConflictMove,
+							},
+							&cli.BoolFlag{
+								Name:  "verify",
+								Usage: "After moving, re-fetch the board and report whether the issue landed in the target pipeline.",
+							},
+							&cli.BoolFlag{
+								Name:  "print-curl",
+								Usage: fmt.Sprintf("Print an equivalent curl command for the move request to stderr, reading the token from $%s.", ZenHubTokenEnvVar),
+							},
+							&cli.BoolFlag{
+								Name:  "output-id-only",
+								Usage: "On success, only print the number of the moved issue.",
+							},
+							&cli.IntFlag{
+								Name:  "epic",
+								Usage: "After moving, add the issue to the epic with this issue number.",
+							},
+							&cli.UintFlag{
+								Name:  "concurrency",
+								Usage: "Number of issues to move in parallel when moving several issues.",
+								Value: DefaultMoveConcurrency,
+							},
+							&cli.BoolFlag{
+								Name:  "fail-fast",
+								Usage: "Stop moving issues after the first one fails to move, reporting the rest as cancelled.",
+							},
+							&cli.UintFlag{
+								Name:  "batch-size",
+								Usage: "Move issues in batches of this many, reporting progress after each, to pace large moves. 0 moves them all in one batch.",
+							},
+							&cli.DurationFlag{
+								Name:  "batch-delay",
+								Usage: "How long to wait between batches of moves, e.g. 10s. Needs --batch-size.",
+							},
+							&cli.BoolFlag{
+								Name:  "json-pretty",
+								Usage: "With --output json, print the results of moving several issues as one indented object grouping them into succeeded, skipped and failed once every move is done, instead of a line per move as it finishes.",
+							},
+							&cli.BoolFlag{
+								Name:  "create-pipeline",
+								Usage: "Create the target pipeline, using the pipeline argument as its name, if it doesn't exist. Needs the GraphQL API, with a zh_ API key or --api graphql.",
+							},
+							&cli.BoolFlag{
+								Name:    "yes",
+								Aliases: []string{"y"},
+								Usage:   "Don't ask for confirmation before creating a pipeline.",
+							},
+						},
+					},
+					{
+						Name:    "undo",
+						Aliases: []string{"mv-back"},
+						Usage:   "Move the issues moved by the last issue mv back to where they were",
+						Action:  UndoMoveCommand,
+					},
+					issueEstimate,
+					{
+						Name:      "info",
+						Usage:     "Show an issue's pipeline, estimate and dependencies",
+						ArgsUsage: "<issue-number>",
+						Action:    IssueInfoCommand,
+					},
+					{
+						Name:      "position",
+						Usage:     "Show the pipeline an issue is in and its index within it",
+						ArgsUsage: "<issue-number>",
+						Action:    IssuePositionCommand,
 					},
 				},
 			},
 			{
-				Name:  "board",
-				Usage: "Work with boards",
+				Name:   "board",
+				Usage:  "Show the issues in each pipeline of the board, or work with boards",
+				Action: ShowBoardCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "pipeline",
+						Usage: "Only show the pipeline with this ID or name",
+					},
+					&cli.BoolFlag{
+						Name:  "no-titles",
+						Usage: "Don't look up issue titles on GitHub",
+					},
+				},
 				Subcommands: []*cli.Command{
 					{
 						Name:   "ls",
 						Usage:  "List all the pipelines in the board",
 						Action: ListBoardCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "sort",
+								Usage: fmt.Sprintf("What to sort the issues in each pipeline by, %s, optionally followed by :asc or :desc. Defaults to board order.", joinAlternatives(BoardIssueSortKeys)),
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "pipeline",
+				Usage: "Work with pipelines",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "ls",
+						Usage:  "List the ID, name and number of issues of each pipeline in the workspace",
+						Action: ListPipelinesCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "sort",
+								Usage: fmt.Sprintf("What to sort the pipelines by, %s, optionally followed by :asc or :desc. Defaults to board order.", joinAlternatives(PipelineSortKeys)),
+							},
+						},
+					},
+					{
+						Name:      "move",
+						Usage:     "Move a pipeline to a new index on the board. Needs the GraphQL API, with a zh_ API key or --api graphql",
+						ArgsUsage: "<pipeline-id> <new-index>",
+						Action:    MovePipelineCommand,
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a pipeline from the board. Needs the GraphQL API, with a zh_ API key or --api graphql",
+						ArgsUsage: "<pipeline-id>",
+						Action:    DeletePipelineCommand,
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Delete the pipeline even if it contains issues.",
+							},
+							&cli.BoolFlag{
+								Name:    "yes",
+								Aliases: []string{"y"},
+								Usage:   "Don't ask for confirmation before deleting the pipeline.",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:   "version",
+				Usage:  "Print the version, git commit and build date of zh",
+				Action: VersionCommand,
+			},
+			{
+				Name:  "config",
+				Usage: "Work with the config file",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "edit",
+						Usage:  "Open the config file in $VISUAL or $EDITOR, creating it from a commented template if it doesn't exist, and check it is valid once the editor exits",
+						Action: EditConfigCommand,
+					},
+				},
+			},
+			{
+				Name:      "completion",
+				Usage:     "Print a shell completion script for bash or zsh, e.g. source <(zh completion bash)",
+				ArgsUsage: "<bash|zsh>",
+				Action:    CompletionCommand,
+			},
+			{
+				Name:  "epic",
+				Usage: "Work with epics",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "ls",
+						Usage:  "List the epics in the repository",
+						Action: ListEpicsCommand,
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "repository-id",
+								Usage: "IDs of the repositories to list the epics of together, repeated or separated by commas, e.g. 123,456. Defaults to the repository given before the command.",
+							},
+							&cli.StringFlag{
+								Name: "sort",
+								Usage: fmt.Sprintf("What to sort the epics by, one of %s, %s, %s or %s (with --with-progress), optionally followed by :asc or :desc. Sorting by %s looks up each epic on GitHub using %s if set.",
+									EpicSortIssueNumber, EpicSortTitle, EpicSortChildren, EpicSortProgress, EpicSortTitle, GitHubTokenEnvVar),
+								Value: EpicSortIssueNumber,
+							},
+							&cli.BoolFlag{
+								Name:  "with-progress",
+								Usage: "Show how many of each epic's issues are closed, which takes a request per epic.",
+							},
+						},
+					},
+					{
+						Name:      "show",
+						Usage:     "Show an epic's pipeline, estimates and issues",
+						ArgsUsage: "<epic-number>",
+						Action:    ShowEpicCommand,
+					},
+					{
+						Name:      "add",
+						Aliases:   []string{"add-issue"},
+						Usage:     "Add issues to an epic",
+						ArgsUsage: "<epic-number> <issue-number>...",
+						Action:    AddEpicIssuesCommand,
+					},
+					{
+						Name:      "rm",
+						Usage:     "Remove issues from an epic",
+						ArgsUsage: "<epic-number> <issue-number>...",
+						Action:    RemoveEpicIssuesCommand,
+					},
+					{
+						Name:      "convert",
+						Usage:     "Convert an issue to an epic, optionally adding issues to it",
+						ArgsUsage: "<issue-number> [<issue-number>...]",
+						Action:    ConvertToEpicCommand,
+					},
+				},
+			},
+			{
+				Name:  "estimate",
+				Usage: "Work with issue estimates",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "get",
+						Usage:     "Print the current estimate of an issue",
+						ArgsUsage: "<issue-number>",
+						Action:    GetEstimateCommand,
+					},
+					NewSetEstimateCommand("set"),
+					{
+						Name:      "clear",
+						Usage:     "Remove the estimate from one or more issues",
+						ArgsUsage: "<issue-number>...",
+						Action:    ClearEstimateCommand,
+					},
+				},
+			},
+			{
+				Name:  "workspace",
+				Usage: "Work with workspaces",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "ls",
+						Usage:  "List the workspaces the repository belongs to",
+						Action: ListWorkspacesCommand,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "sort",
+								Usage: fmt.Sprintf("What to sort the workspaces by, %s, optionally followed by :asc or :desc. Defaults to the order ZenHub lists them in.", joinAlternatives(WorkspaceSortKeys)),
+							},
+						},
+					},
+					{
+						Name:   "pipelines",
+						Usage:  "List the pipelines in the workspace",
+						Action: ListWorkspacePipelinesCommand,
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "json-map",
+								Usage: "Print the pipelines as a JSON object mapping names to IDs.",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "sprint",
+				Usage: "Work with sprints",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "current",
+						Usage:  "Show the currently active sprint and its issues. Needs the GraphQL API, with a zh_ API key or --api graphql",
+						Action: CurrentSprintCommand,
+					},
+				},
+			},
+			{
+				Name:   "export",
+				Usage:  "Export the pipeline and estimate of every issue on the board",
+				Action: ExportCommand,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: fmt.Sprintf("Export format, one of %s, %s or %s. Defaults to the --output format if it is %s or %s, otherwise %s.", OutputJSON, OutputYAML, OutputCSV, OutputJSON, OutputYAML, OutputJSON),
+					},
+					&cli.StringFlag{
+						Name:  "output-file",
+						Usage: "Write the export to the given file instead of stdout.",
+					},
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Move issues and set estimates to match a snapshot written by export",
+				ArgsUsage: "<file>",
+				Action:    ImportCommand,
+				Flags: []cli.Flag{
+					&cli.UintFlag{
+						Name:  "concurrency",
+						Usage: "Number of issues to update in parallel. Use 1 to also restore the order of issues within pipelines.",
+						Value: DefaultImportConcurrency,
+					},
+				},
+			},
+			{
+				Name:   "health",
+				Usage:  "Check the ZenHub API is reachable within a latency budget",
+				Action: HealthCommand,
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "max-latency",
+						Usage: "Maximum acceptable latency of the ZenHub API.",
+						Value: DefaultMaxLatency,
 					},
 				},
 			},
 		},
 	}
 
-	if err := app.Run(os.Args); err != nil {
-		logrus.WithFields(logrus.Fields{"error": err}).Fatal("Failed to run app")
+	return app
+}
+
+func main() {
+	logrus.AddHook(redactionHook)
+
+	// Anything logged while completing would be printed over the user's
+	// command line.
+	if len(os.Args) > 1 && os.Args[len(os.Args)-1] == "--generate-bash-completion" {
+		logrus.SetOutput(ioutil.Discard)
+	}
+
+	if err := dotenv.Load(); err != nil {
+		logrus.WithField("error", err).Warn("failed to load .env file in working directory")
+	}
+
+	SetLogLevelFromEnv()
+
+	// The log format is set as early as possible so nothing is logged in
+	// the wrong format, and again in `Before` in case the flag overrides it.
+	if format := os.Getenv(ZenHubLogFormatEnvVar); format != "" {
+		if err := SetLogFormat(format); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"env_var": ZenHubLogFormatEnvVar,
+				"error":   err,
+			}).Warn("Ignoring invalid log format")
+		}
+	}
+
+	app := NewApp()
+
+	ctx, stop := NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		// The output format is only set once the flags are parsed, so
+		// errors parsing the global flags are logged rather than printed.
+		structuredOutput := outputFormat != OutputTable
+		exitCode := 1
+		if ctx.Err() != nil {
+			exitCode = ExitCodeInterrupted
+		}
+		var reported *ReportedError
+		if structuredOutput && !errors.As(err, &reported) {
+			if err := PrintStructured(ErrorResult{Error: redactionHook.Redact(err.Error())}); err != nil {
+				logrus.WithFields(logrus.Fields{"error": err}).Error("Failed to print error")
+			}
+		} else {
+			logrus.WithFields(logrus.Fields{"error": err}).Error("Failed to run app")
+		}
+		stop()
+		os.Exit(exitCode)
 	}
 }
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewBatchMoveResult(t *testing.T) {
+	notFound := &zenhub.StatusError{StatusCode: http.StatusNotFound, Err: errors.New("issue not found")}
+	mover := &IssueMover{pipelineID: "p1"}
+	results := []MoveResult{
+		mover.newResult(1, MoveStatusMoved),
+		mover.newResult(2, MoveStatusSkipped),
+		failedResult(mover.newResult(3, MoveStatusMoved), fmt.Errorf("failed to move issue 3: %w", notFound)),
+		failedResult(mover.newResult(4, MoveStatusMoved), errors.New("WIP limit reached")),
+		mover.newResult(5, MoveStatusCancelled),
+		mover.newResult(6, MoveStatusPlanned),
+	}
+
+	batch := NewBatchMoveResult(results)
+	if want := []MoveResult{results[0], results[5]}; !reflect.DeepEqual(batch.Succeeded, want) {
+		t.Errorf("expected succeeded %+v, got %+v", want, batch.Succeeded)
+	}
+	if want := []MoveResult{results[1], results[4]}; !reflect.DeepEqual(batch.Skipped, want) {
+		t.Errorf("expected skipped %+v, got %+v", want, batch.Skipped)
+	}
+	wantFailed := []MoveFailure{
+		{IssueNumber: 3, Status: http.StatusNotFound, Message: "failed to move issue 3: issue not found"},
+		{IssueNumber: 4, Message: "WIP limit reached"},
+	}
+	if !reflect.DeepEqual(batch.Failed, wantFailed) {
+		t.Errorf("expected failed %+v, got %+v", wantFailed, batch.Failed)
+	}
+}
+
+func TestNewBatchMoveResultEmptyArrays(t *testing.T) {
+	body, err := json.Marshal(NewBatchMoveResult(nil))
+	if err != nil {
+		t.Fatalf("failed to marshal batch result: %v", err)
+	}
+	if want := `{"succeeded":[],"skipped":[],"failed":[]}`; string(body) != want {
+		t.Errorf("expected %s, got %s", want, body)
+	}
+}
+
+// testToken is the ZenHub token commands are run with in tests.
+const testToken = "abcdefghijklmnopqrstuvwxyz"
+
+// testPipelineID is a pipeline ID commands accept without looking it up.
+const testPipelineID = "5e8f4a3b2c1d0e9f8a7b6c5d"
+
+// setenv sets the environment variable for the rest of the test, unsetting
+// it if the value is empty.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	previous, set := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+	t.Cleanup(func() {
+		if set {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// recordedRequest is a request received by a test server.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Token  string
+	Body   string
+}
+
+// zenHubServer is a fake ZenHub API for commands to be run against.
+type zenHubServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+// Requests returns the requests the server has received, in order.
+func (s *zenHubServer) Requests() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+// withZenHubServer starts a fake ZenHub API that records each request and
+// answers it with `handler`, and isolates commands run by `runApp` from the
+// user's token, config file, state and GitHub.
+func withZenHubServer(t *testing.T, handler http.HandlerFunc) *zenHubServer {
+	t.Helper()
+	server := &zenHubServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		server.mu.Lock()
+		server.requests = append(server.requests, recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Token:  r.Header.Get(AuthenticationHeader),
+			Body:   string(body),
+		})
+		server.mu.Unlock()
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	setenv(t, ZenHubTokenEnvVar, testToken)
+	setenv(t, GitHubTokenEnvVar, "")
+	setenv(t, ZenHubAuthHeaderEnvVar, "")
+	setenv(t, "XDG_CONFIG_HOME", t.TempDir())
+	setenv(t, "XDG_STATE_HOME", t.TempDir())
+	return server
+}
+
+// runApp runs zh with the given arguments against the server.
+func runApp(t *testing.T, server *zenHubServer, args ...string) error {
+	t.Helper()
+	args = append([]string{"zh", "--base-url", server.URL}, args...)
+	return NewApp().RunContext(context.Background(), args)
+}
+
+func TestMoveIssueCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		wantErr    string
+	}{
+		{name: "success", statusCode: http.StatusOK, body: `{}`},
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, wantErr: "authentication token is not valid"},
+		{name: "forbidden", statusCode: http.StatusForbidden, body: `{"message": "You do not have permission to access this workspace"}`, wantErr: "permission denied"},
+		{name: "not found", statusCode: http.StatusNotFound, wantErr: "endpoint not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					// The issue's current pipeline, recorded to undo the move.
+					fmt.Fprint(w, `{"pipeline": {"name": "Backlog", "pipeline_id": "p1", "workspace_id": "ws1"}}`)
+					return
+				}
+				w.WriteHeader(test.statusCode)
+				fmt.Fprint(w, test.body)
+			})
+
+			err := runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "42", testPipelineID)
+			if test.wantErr == "" && err != nil {
+				t.Fatalf("failed to move issue: %v", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("expected an error containing %q, got: %v", test.wantErr, err)
+			}
+
+			var moves []recordedRequest
+			for _, request := range server.Requests() {
+				if request.Token != testToken {
+					t.Errorf("expected %s %s to be authenticated with the token, got %q", request.Method, request.Path, request.Token)
+				}
+				if request.Method == http.MethodPost {
+					moves = append(moves, request)
+				}
+			}
+			if len(moves) != 1 {
+				t.Fatalf("expected 1 move request, got %d", len(moves))
+			}
+			if want := "/p2/workspaces/ws1/repositories/1/issues/42/moves"; moves[0].Path != want {
+				t.Errorf("expected a move request to %s, got %s", want, moves[0].Path)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal([]byte(moves[0].Body), &body); err != nil {
+				t.Fatalf("failed to decode move request body %s: %v", moves[0].Body, err)
+			}
+			want := map[string]interface{}{"pipeline_id": testPipelineID, "position": "bottom"}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("expected move request body %v, got %v", want, body)
+			}
+		})
+	}
+}
+
+func TestSetLogLevelFromEnv(t *testing.T) {
+	logs := captureLogs(t)
+
+	setenv(t, ZenHubLogLevelEnvVar, "verbose")
+	SetLogLevelFromEnv()
+	for _, want := range []string{"Invalid log level", "value=verbose", "env_var=" + ZenHubLogLevelEnvVar} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the warning to contain %q, got: %s", want, logs.String())
+		}
+	}
+	if strings.Contains(logs.String(), "%s") {
+		t.Errorf("expected no unsubstituted placeholders, got: %s", logs.String())
+	}
+
+	setenv(t, ZenHubLogLevelEnvVar, "debug")
+	SetLogLevelFromEnv()
+	if level := logrus.GetLevel(); level != logrus.DebugLevel {
+		t.Errorf("expected log level debug, got %s", level)
+	}
+}
+
+func TestMoveIssueCommandIssueNumber(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+
+	if err := runApp(t, server, "--repository-id", "7", "--workspace-id", "ws1", "issue", "mv", "#42", testPipelineID); err != nil {
+		t.Fatalf("failed to move issue: %v", err)
+	}
+	want := "/p2/workspaces/ws1/repositories/7/issues/42/moves"
+	for _, request := range server.Requests() {
+		if request.Method == http.MethodPost && request.Path == want {
+			return
+		}
+	}
+	t.Errorf("expected a move request to %s, got %+v", want, server.Requests())
+}
+
+func TestGetZenHubToken(t *testing.T) {
+	token := fileConfig.Token
+	fileConfig.Token = ""
+	t.Cleanup(func() { fileConfig.Token = token })
+
+	whitespaceFile := t.TempDir() + "/token"
+	if err := ioutil.WriteFile(whitespaceFile, []byte(" \n\t\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		env       string
+		tokenFile string
+		want      string
+		wantErr   string
+		wantWarn  bool
+	}{
+		{name: "empty", wantErr: "expected environment variable " + ZenHubTokenEnvVar},
+		{name: "whitespace only", env: " \t ", wantErr: "expected environment variable " + ZenHubTokenEnvVar},
+		{name: "whitespace only file", tokenFile: whitespaceFile, wantErr: "is empty"},
+		{name: "valid", env: testToken, want: testToken},
+		{name: "valid with surrounding whitespace", env: " " + testToken + "\n", want: testToken},
+		{name: "trailing quote", env: testToken + `"`, wantErr: "looks malformed"},
+		{name: "inner whitespace", env: "abcdefghij klmnopqrstuvwxyz", wantErr: "looks malformed"},
+		{name: "short", env: "abc123", want: "abc123", wantWarn: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			setenv(t, ZenHubTokenEnvVar, test.env)
+
+			got, err := GetZenHubToken(test.tokenFile)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("expected an error containing %q, got: %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get token: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("expected token %q, got %q", test.want, got)
+			}
+			if warned := strings.Contains(logs.String(), "doesn't look like a ZenHub token"); warned != test.wantWarn {
+				t.Errorf("expected warning %t, got logs: %s", test.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+// captureStdout returns what `run` prints to stdout.
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	format := outputFormat
+	defer func() {
+		os.Stdout = stdout
+		outputFormat = format
+	}()
+
+	output := make(chan string)
+	go func() {
+		body, _ := ioutil.ReadAll(r)
+		output <- string(body)
+	}()
+	run()
+	w.Close()
+	return <-output
+}
+
+// batchMoveServer answers move requests for issue 2 with 404 and the rest
+// with success.
+func batchMoveServer(t *testing.T) *zenHubServer {
+	return withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/issues/2/moves") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+}
+
+func TestMoveIssueCommandStreamsJSON(t *testing.T) {
+	server := batchMoveServer(t)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runApp(t, server, "--output", "json", "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "1", "2", "3", testPipelineID)
+	})
+	var reported *ReportedError
+	if !errors.As(err, &reported) {
+		t.Fatalf("expected an error already reported by the output, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	statuses := make(map[int]MoveResult, len(lines))
+	for _, line := range lines {
+		var result MoveResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("expected a move result per line, got %q: %v", line, err)
+		}
+		statuses[result.IssueID] = result
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected a result for each of 3 issues, got: %s", output)
+	}
+	for issueID, want := range map[int]string{1: MoveStatusMoved, 2: MoveStatusFailed, 3: MoveStatusMoved} {
+		if statuses[issueID].Status != want {
+			t.Errorf("expected issue %d to be %s, got %+v", issueID, want, statuses[issueID])
+		}
+	}
+	if statuses[2].StatusCode != http.StatusNotFound || statuses[2].Error == "" {
+		t.Errorf("expected issue 2 to fail with a 404 and its error, got %+v", statuses[2])
+	}
+}
+
+func TestMoveIssueCommandJSONPretty(t *testing.T) {
+	server := batchMoveServer(t)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runApp(t, server, "--output", "json", "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--json-pretty", "1", "2", "3", testPipelineID)
+	})
+	if err == nil {
+		t.Fatal("expected the failed move to fail the command")
+	}
+
+	var batch BatchMoveResult
+	if err := json.Unmarshal([]byte(output), &batch); err != nil {
+		t.Fatalf("expected one batch result, got %q: %v", output, err)
+	}
+	if !strings.Contains(output, "\n  ") {
+		t.Errorf("expected indented JSON, got: %s", output)
+	}
+	if len(batch.Succeeded) != 2 || len(batch.Skipped) != 0 || len(batch.Failed) != 1 {
+		t.Fatalf("expected 2 succeeded and 1 failed, got %+v", batch)
+	}
+	if failure := batch.Failed[0]; failure.IssueNumber != 2 || failure.Status != http.StatusNotFound {
+		t.Errorf("expected issue 2 to fail with a 404, got %+v", failure)
+	}
+
+	captureStdout(t, func() {
+		err = runApp(t, server, "--output", "yaml", "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--json-pretty", "1", testPipelineID)
+	})
+	if err == nil || !strings.Contains(err.Error(), "json-pretty needs output to be json") {
+		t.Errorf("expected json-pretty to need JSON output, got: %v", err)
+	}
+}
+
+func TestMoveIssueCommandBatches(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	logs := captureLogs(t)
+
+	start := time.Now()
+	err := runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--batch-size", "2", "--batch-delay", "50ms", "1", "2", "3", "4", "5", testPipelineID)
+	if err != nil {
+		t.Fatalf("failed to move issues: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected 2 pauses between 3 batches, took %s", elapsed)
+	}
+
+	moved := 0
+	for _, request := range server.Requests() {
+		if strings.HasSuffix(request.Path, "/moves") {
+			moved++
+		}
+	}
+	if moved != 5 {
+		t.Errorf("expected 5 moves, got %d", moved)
+	}
+	if count := strings.Count(logs.String(), "Finished batch of moves"); count != 3 {
+		t.Errorf("expected progress after each of 3 batches, got %d in: %s", count, logs)
+	}
+	if !strings.Contains(logs.String(), "issues=5 total=5") {
+		t.Errorf("expected the last batch to report every issue moved, got: %s", logs)
+	}
+
+	err = runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--batch-delay", "1s", "1", testPipelineID)
+	if err == nil || !strings.Contains(err.Error(), "batch-delay needs batch-size to be set") {
+		t.Errorf("expected batch-delay to need batch-size, got: %v", err)
+	}
+}
+
+func TestGraphQLOnlyCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "pipeline move", args: []string{"pipeline", "move", testPipelineID, "0"}, want: "pipeline move requires a GraphQL API key"},
+		{name: "create pipeline", args: []string{"issue", "mv", "--create-pipeline", "42", "New pipeline"}, want: "create-pipeline requires a GraphQL API key"},
+		{name: "pipeline delete", args: []string{"pipeline", "delete", testPipelineID}, want: "pipeline delete requires a GraphQL API key"},
+		{name: "sprint current", args: []string{"sprint", "current"}, want: "sprint current requires a GraphQL API key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{}`)
+			})
+
+			args := append([]string{"--api", "rest", "--repository-id", "1", "--workspace-id", "ws1"}, test.args...)
+			err := runApp(t, server, args...)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("expected an error containing %q, got: %v", test.want, err)
+			}
+			if requests := server.Requests(); len(requests) != 0 {
+				t.Errorf("expected no requests, got %v", requests)
+			}
+		})
+	}
+}
+
+func TestSetEstimateCommandAlias(t *testing.T) {
+	for _, args := range [][]string{{"estimate", "set"}, {"issue", "estimate"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{}`)
+			})
+
+			args := append([]string{"--api", "rest", "--repository-id", "1"}, append(args, "--force", "42", "3")...)
+			if err := runApp(t, server, args...); err != nil {
+				t.Fatalf("failed to set estimate: %v", err)
+			}
+			requests := server.Requests()
+			if len(requests) != 1 || requests[0].Method != http.MethodPut || !strings.Contains(requests[0].Path, "/issues/42/estimate") {
+				t.Errorf("expected one request setting the estimate of issue 42, got %v", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultMoveSuccessMessage is the message printed for each moved issue
+// unless `messages.move_success` in the config file overrides it.
+const DefaultMoveSuccessMessage = "Successfully moved issue {{.IssueNumber}} to pipeline {{.PipelineID}}"
+
+// MessagesConfig is the `messages` section of the config file. Each message
+// is a Go template, executed with the data documented on its type.
+type MessagesConfig struct {
+	// MoveSuccess is printed for each moved issue, with `MoveMessageData`.
+	MoveSuccess string `yaml:"move_success"`
+}
+
+// MoveMessageData is the data the move success message is executed with.
+type MoveMessageData struct {
+	IssueNumber int
+	PipelineID  string
+}
+
+// moveSuccessTemplate is the parsed move success message, set by
+// `SetupMessages`.
+var moveSuccessTemplate = template.Must(template.New("move_success").Option("missingkey=error").Parse(DefaultMoveSuccessMessage))
+
+// SetupMessages parses the messages from the config file, falling back to the
+// defaults for the ones it doesn't set. Invalid templates are errors now
+// rather than when the message is first printed.
+func SetupMessages(messages MessagesConfig) error {
+	text := DefaultMoveSuccessMessage
+	if strings.TrimSpace(messages.MoveSuccess) != "" {
+		text = messages.MoveSuccess
+	}
+
+	tmpl, err := template.New("move_success").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("move_success: %w", err)
+	}
+	if err := tmpl.Execute(&strings.Builder{}, MoveMessageData{}); err != nil {
+		return fmt.Errorf("move_success: %w", err)
+	}
+	moveSuccessTemplate = tmpl
+	return nil
+}
+
+// MoveSuccessMessage returns the message printed for an issue moved to the
+// given pipeline.
+func MoveSuccessMessage(issueNumber int, pipelineID string) string {
+	var message strings.Builder
+	data := MoveMessageData{IssueNumber: issueNumber, PipelineID: pipelineID}
+	if err := moveSuccessTemplate.Execute(&message, data); err != nil {
+		// The template was checked against the same data when it was
+		// parsed, so this shouldn't happen.
+		return fmt.Sprintf("Successfully moved issue %d to pipeline %s", issueNumber, pipelineID)
+	}
+	return strings.TrimRight(message.String(), "\n")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMoveSuccessMessage(t *testing.T) {
+	t.Cleanup(func() { SetupMessages(MessagesConfig{}) })
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default", want: "Successfully moved issue 42 to pipeline p1"},
+		{name: "custom", template: "#{{.IssueNumber}} -> {{.PipelineID}}", want: "#42 -> p1"},
+		{name: "trailing newline", template: "moved {{.IssueNumber}}\n", want: "moved 42"},
+		{name: "unknown field", template: "{{.Pipeline}}", wantErr: true},
+		{name: "invalid syntax", template: "{{.IssueNumber", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetupMessages(MessagesConfig{})
+			err := SetupMessages(MessagesConfig{MoveSuccess: test.template})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got: %v", test.wantErr, err)
+			}
+			if test.wantErr {
+				return
+			}
+			if got := MoveSuccessMessage(42, "p1"); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseConfigMessages(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader("messages:\n  move_success: moved\nprofiles:\n  work:\n    messages:\n      move_success: done\n"))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if config.Messages.MoveSuccess != "moved" {
+		t.Errorf("expected move_success of moved, got %q", config.Messages.MoveSuccess)
+	}
+	work, err := config.WithProfile("work")
+	if err != nil {
+		t.Fatalf("failed to apply profile: %v", err)
+	}
+	if work.Messages.MoveSuccess != "done" {
+		t.Errorf("expected the profile's move_success of done, got %q", work.Messages.MoveSuccess)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// MilestoneStatusSet is the status of an issue whose milestone was set.
+	MilestoneStatusSet string = "set"
+
+	// MilestoneStatusPlanned is the status of an issue whose milestone would
+	// have been set if it wasn't a dry run.
+	MilestoneStatusPlanned string = "planned"
+
+	// MilestoneStatusFailed is the status of an issue whose milestone failed
+	// to be set.
+	MilestoneStatusFailed string = "failed"
+)
+
+// MilestoneResult is the structured output of setting the milestone of a
+// moved issue with `set-milestone`, reported apart from the move itself.
+type MilestoneResult struct {
+	IssueNumber int    `json:"issue_number"`
+	Milestone   string `json:"milestone"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// GitHubMilestone is the part of a GitHub milestone zh uses.
+type GitHubMilestone struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+}
+
+// milestonesPageSize is the number of milestones requested per page, the
+// most GitHub allows.
+const milestonesPageSize = 100
+
+// ResolveMilestone returns the milestone titled `title`, matched ignoring
+// case, in the repository with the given ID, creating it if it doesn't exist
+// and `create` is set.
+func (c *GitHubClient) ResolveMilestone(repositoryID uint, title string, create bool) (*GitHubMilestone, error) {
+	milestone, err := c.FindMilestone(repositoryID, title)
+	if err != nil {
+		return nil, err
+	}
+	if milestone != nil {
+		return milestone, nil
+	}
+	if !create {
+		return nil, fmt.Errorf("milestone %s not found in repository %d, use create-milestone to create it", title, repositoryID)
+	}
+	return c.CreateMilestone(repositoryID, title)
+}
+
+// FindMilestone looks up the milestone titled `title`, matched ignoring
+// case, among the open and closed milestones of the repository with the
+// given ID. It returns nil if there is no such milestone.
+func (c *GitHubClient) FindMilestone(repositoryID uint, title string) (*GitHubMilestone, error) {
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repositories/%d/milestones?state=all&per_page=%d&page=%d", GitHubBaseURL, repositoryID, milestonesPageSize, page)
+		logrus.WithField("url", url).Debug("Sending list GitHub milestones request")
+		resp, err := c.send(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list milestones from GitHub: %w", err)
+		}
+
+		var milestones []GitHubMilestone
+		err = gitHubResponseError(resp, "list milestones")
+		if err == nil {
+			if decodeErr := json.NewDecoder(resp.Body).Decode(&milestones); decodeErr != nil {
+				err = fmt.Errorf("failed to decode GitHub milestones: %w", decodeErr)
+			}
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range milestones {
+			if strings.EqualFold(milestones[i].Title, title) {
+				return &milestones[i], nil
+			}
+		}
+		if len(milestones) < milestonesPageSize {
+			return nil, nil
+		}
+	}
+}
+
+// CreateMilestone creates an open milestone titled `title` in the repository
+// with the given ID.
+func (c *GitHubClient) CreateMilestone(repositoryID uint, title string) (*GitHubMilestone, error) {
+	url := fmt.Sprintf("%s/repositories/%d/milestones", GitHubBaseURL, repositoryID)
+	logrus.WithFields(logrus.Fields{
+		"url":   url,
+		"title": title,
+	}).Debug("Sending create GitHub milestone request")
+	resp, err := c.send(http.MethodPost, url, map[string]string{"title": title})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create milestone %s on GitHub: %w", title, err)
+	}
+	defer resp.Body.Close()
+	if err := gitHubResponseError(resp, fmt.Sprintf("create milestone %s", title)); err != nil {
+		return nil, err
+	}
+
+	var milestone GitHubMilestone
+	if err := json.NewDecoder(resp.Body).Decode(&milestone); err != nil {
+		return nil, fmt.Errorf("failed to decode GitHub milestone %s: %w", title, err)
+	}
+	if milestone.Title == "" {
+		// A dry run answers with an empty milestone.
+		milestone.Title = title
+	}
+	return &milestone, nil
+}
+
+// SetIssueMilestone sets the milestone of the issue with the given number in
+// the repository with the given ID to the milestone with the given number.
+func (c *GitHubClient) SetIssueMilestone(repositoryID uint, issueNumber, milestoneNumber int) error {
+	url := fmt.Sprintf("%s/repositories/%d/issues/%d", GitHubBaseURL, repositoryID, issueNumber)
+	logrus.WithFields(logrus.Fields{
+		"url":       url,
+		"milestone": milestoneNumber,
+	}).Debug("Sending set GitHub issue milestone request")
+	resp, err := c.send(http.MethodPatch, url, map[string]int{"milestone": milestoneNumber})
+	if err != nil {
+		return fmt.Errorf("failed to set milestone of issue %d on GitHub: %w", issueNumber, err)
+	}
+	defer resp.Body.Close()
+	return gitHubResponseError(resp, fmt.Sprintf("set milestone of issue %d", issueNumber))
+}
+
+// gitHubResponseError converts an unsuccessful response from GitHub to the
+// request to do `action` into an error explaining it.
+func gitHubResponseError(resp *http.Response, action string) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == 401:
+		return fmt.Errorf("failed to %s on GitHub: token is not valid. Check that %s is set correctly", action, GitHubTokenEnvVar)
+	case resp.StatusCode == 403 || resp.StatusCode == 404:
+		return fmt.Errorf("failed to %s on GitHub: not found or not allowed. Check that %s has write access to the repository", action, GitHubTokenEnvVar)
+	case resp.StatusCode == 422:
+		return fmt.Errorf("failed to %s on GitHub: rejected as invalid", action)
+	default:
+		return fmt.Errorf("failed to %s on GitHub: unexpected status code %d", action, resp.StatusCode)
+	}
+}
+
+// MilestoneSetter sets the milestone given by `set-milestone` on issues
+// after they are moved.
+type MilestoneSetter struct {
+	github       *GitHubClient
+	repositoryID uint
+	milestone    *GitHubMilestone
+	dryRun       bool
+}
+
+// NewMilestoneSetterFromContext resolves the milestone given by the
+// `set-milestone` flag, creating it if `create-milestone` is set. It returns
+// nil if `set-milestone` isn't set.
+//
+// Milestones are resolved before any issue is moved, so a missing milestone
+// doesn't leave the issues moved but without it.
+func NewMilestoneSetterFromContext(ctx *cli.Context, repositoryID uint) (*MilestoneSetter, error) {
+	title := strings.TrimSpace(ctx.String("set-milestone"))
+	if title == "" {
+		if ctx.Bool("create-milestone") {
+			return nil, fmt.Errorf("create-milestone needs set-milestone to be set")
+		}
+		return nil, nil
+	}
+	if strings.TrimSpace(os.Getenv(GitHubTokenEnvVar)) == "" {
+		return nil, fmt.Errorf("set-milestone needs %s to be set to change issues on GitHub", GitHubTokenEnvVar)
+	}
+
+	github, err := NewGitHubClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	milestone, err := github.ResolveMilestone(repositoryID, title, ctx.Bool("create-milestone"))
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"milestone":        milestone.Title,
+		"milestone_number": milestone.Number,
+	}).Debug("Resolved milestone")
+
+	return &MilestoneSetter{
+		github:       github,
+		repositoryID: repositoryID,
+		milestone:    milestone,
+		dryRun:       ctx.Bool("dry-run"),
+	}, nil
+}
+
+// Set sets the milestone on the given issue.
+func (s *MilestoneSetter) Set(issueNumber int) MilestoneResult {
+	result := MilestoneResult{IssueNumber: issueNumber, Milestone: s.milestone.Title, Status: MilestoneStatusSet}
+	if err := s.github.SetIssueMilestone(s.repositoryID, issueNumber, s.milestone.Number); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"issue_id":  issueNumber,
+			"milestone": s.milestone.Title,
+			"error":     err,
+		}).Error("Failed to set milestone")
+		result.Status = MilestoneStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	if s.dryRun {
+		result.Status = MilestoneStatusPlanned
+	}
+	return result
+}
+
+// PrintMilestoneResult prints the result of setting an issue's milestone as
+// text. Failures are logged by `MilestoneSetter.Set` instead.
+func PrintMilestoneResult(result MilestoneResult) {
+	switch result.Status {
+	case MilestoneStatusSet:
+		fmt.Printf("Successfully set milestone %s on issue %d\n", result.Milestone, result.IssueNumber)
+	case MilestoneStatusPlanned:
+		fmt.Printf("Would set milestone %s on issue %d\n", result.Milestone, result.IssueNumber)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResolveMilestone(t *testing.T) {
+	var created, set []string
+	client := withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repositories/1/milestones":
+			if r.URL.Query().Get("state") != "all" {
+				t.Errorf("expected open and closed milestones to be listed, got state %s", r.URL.Query().Get("state"))
+			}
+			// The first page is full, so the second is asked for too.
+			if r.URL.Query().Get("page") == "1" {
+				milestones := make([]string, 0, milestonesPageSize)
+				for i := 0; i < milestonesPageSize; i++ {
+					milestones = append(milestones, fmt.Sprintf(`{"number": %d, "title": "v%d"}`, i+1, i+1))
+				}
+				fmt.Fprintf(w, "[%s]", strings.Join(milestones, ","))
+				return
+			}
+			fmt.Fprint(w, `[{"number": 101, "title": "Sprint 5", "state": "closed"}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/repositories/1/milestones":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body["title"])
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"number": 102, "title": %q}`, body["title"])
+		case r.Method == http.MethodPatch && r.URL.Path == "/repositories/1/issues/7":
+			var body map[string]int
+			json.NewDecoder(r.Body).Decode(&body)
+			set = append(set, fmt.Sprint(body["milestone"]))
+			fmt.Fprint(w, `{}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/repositories/1/issues/8":
+			http.NotFound(w, r)
+		default:
+			t.Errorf("unexpected %s request to %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	milestone, err := client.ResolveMilestone(1, "sprint 5", false)
+	if err != nil || milestone.Number != 101 {
+		t.Fatalf("expected milestone 101 from the second page, got %+v: %v", milestone, err)
+	}
+
+	if _, err := client.ResolveMilestone(1, "Sprint 6", false); err == nil || !strings.Contains(err.Error(), "create-milestone") {
+		t.Errorf("expected a missing milestone to suggest create-milestone, got: %v", err)
+	}
+	if len(created) != 0 {
+		t.Errorf("expected no milestone to be created without create-milestone, got %v", created)
+	}
+
+	milestone, err = client.ResolveMilestone(1, "Sprint 6", true)
+	if err != nil || milestone.Number != 102 || len(created) != 1 || created[0] != "Sprint 6" {
+		t.Fatalf("expected Sprint 6 to be created as milestone 102, got %+v, created %v: %v", milestone, created, err)
+	}
+
+	setter := &MilestoneSetter{github: client, repositoryID: 1, milestone: milestone}
+	result := setter.Set(7)
+	if result.Status != MilestoneStatusSet || len(set) != 1 || set[0] != "102" {
+		t.Errorf("expected milestone 102 to be set on issue 7, got %+v with requests %v", result, set)
+	}
+	if result := setter.Set(8); result.Status != MilestoneStatusFailed || result.Error == "" {
+		t.Errorf("expected setting the milestone of a missing issue to fail, got %+v", result)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// OutputTable is the default output format, human readable text with
+	// results aligned in columns.
+	OutputTable string = "table"
+
+	// OutputText is an alias of `OutputTable`, kept for scripts written
+	// before table was the name of the format.
+	OutputText string = "text"
+
+	// OutputJSON is the output format for machine readable JSON output.
+	OutputJSON string = "json"
+
+	// OutputYAML is the output format for machine readable YAML output.
+	OutputYAML string = "yaml"
+)
+
+// outputFormat is the output format from the `output` flag, set once the
+// flags are parsed.
+var outputFormat = OutputTable
+
+// ErrorResult is the structured output of a failed command.
+type ErrorResult struct {
+	Error string `json:"error"`
+}
+
+// ReportedError is an error a command has already described in its
+// structured output, so it isn't printed again as an `ErrorResult`.
+type ReportedError struct {
+	Err error
+}
+
+func (e *ReportedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReportedError) Unwrap() error {
+	return e.Err
+}
+
+// ResolvedIDs are the IDs of the entities a command acted on, after any
+// resolution (e.g. inferring the workspace from the repository).
+type ResolvedIDs struct {
+	WorkspaceID  string `json:"workspace_id,omitempty"`
+	RepositoryID uint   `json:"repository_id,omitempty"`
+	PipelineID   string `json:"pipeline_id,omitempty"`
+}
+
+// SetOutputFormat checks the `output` flag is a supported output format and
+// makes it the format `PrintStructured` writes.
+func SetOutputFormat(ctx *cli.Context) error {
+	switch output := ctx.String("output"); output {
+	case OutputTable, OutputText:
+		outputFormat = OutputTable
+	case OutputJSON, OutputYAML:
+		outputFormat = output
+	default:
+		return fmt.Errorf("invalid output value of %s, expected one of %s, %s or %s", output, OutputTable, OutputJSON, OutputYAML)
+	}
+	return nil
+}
+
+// IsStructuredOutput returns whether machine readable output, JSON or YAML,
+// was requested.
+func IsStructuredOutput(ctx *cli.Context) bool {
+	switch ctx.String("output") {
+	case OutputJSON, OutputYAML:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsQuiet returns whether the `quiet` flag was set to suppress messages
+// reporting success. It doesn't affect structured output.
+func IsQuiet(ctx *cli.Context) bool {
+	return ctx.Bool("quiet")
+}
+
+// PrintStructured prints the given value to stdout in the structured output
+// format: a line of JSON or a YAML document.
+func PrintStructured(v interface{}) error {
+	if outputFormat == OutputYAML {
+		body, err := MarshalYAML(v)
+		if err != nil {
+			return fmt.Errorf("failed to convert output to YAML: %w", err)
+		}
+		fmt.Printf("---\n%s", body)
+		return nil
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to convert output to JSON: %w", err)
+	}
+	fmt.Println(string(body))
+	return nil
+}
+
+// MarshalYAML converts the given value to YAML by way of its JSON encoding,
+// so the field names, field order and `omitempty` rules of JSON output apply.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// decodeOrdered decodes the next JSON value, decoding objects to a
+// `yaml.MapSlice` so their fields keep their order.
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := yaml.MapSlice{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := decoder.Token()
+		return list, err
+	default:
+		return token, nil
+	}
+}
+
+// PrintJSONIndented prints the given value to stdout as indented JSON, for
+// output that is read whole rather than line by line.
+func PrintJSONIndented(v interface{}) error {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert output to JSON: %w", err)
+	}
+	fmt.Println(string(body))
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"gopkg.in/yaml.v2"
+)
+
+// snakeCase matches the keys of structured output.
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// jsonKeys returns the keys `encoding/json` gives the fields of the given
+// struct, including those of embedded structs it flattens, in sorted order.
+func jsonKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			keys = append(keys, jsonKeys(field.Type)...)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestStructuredOutputKeys(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		keys  []string
+	}{
+		{BatchMoveResult{}, []string{"failed", "github_project", "milestones", "skipped", "succeeded", "verification"}},
+		{BoardView{}, []string{"pipelines", "resolved"}},
+		{ClearedEstimate{}, []string{"error", "issue_number", "status"}},
+		{DeletedPipeline{}, []string{"id", "issue_count", "name", "resolved"}},
+		{EpicInfo{}, []string{"epic_number", "estimate", "issues", "pipeline_id", "pipeline_name", "resolved", "total_estimate"}},
+		{EpicIssueInfo{}, []string{"estimate", "is_epic", "issue_number", "pipeline_id", "pipeline_name", "repository_id"}},
+		{EpicIssuesResult{}, []string{"epic_id", "issue_ids"}},
+		{EpicSummary{}, []string{"children", "issue_number", "issue_url", "progress", "repo_id", "title"}},
+		{ErrorResult{}, []string{"error"}},
+		{ExportedIssue{}, []string{"estimate", "issue_number", "pipeline_id", "pipeline_name", "position"}},
+		{HealthCheck{}, []string{"latency_ms", "max_latency_ms", "status_code", "url"}},
+		{ImportResult{}, []string{"estimated", "failed", "moved", "planned", "resolved", "unchanged"}},
+		{IssueEstimate{}, []string{"estimate", "issue_number", "resolved"}},
+		{IssueInfo{}, []string{"blocked_by", "blocking", "estimate", "is_epic", "issue_number", "pipeline_id", "pipeline_name", "resolved"}},
+		{IssuePosition{}, []string{"index", "issue_number", "pipeline_id", "pipeline_name", "resolved"}},
+		{IssueVerification{}, []string{"actual_pipeline_id", "expected_pipeline_id", "issue_number", "landed"}},
+		{IssueView{}, []string{"estimate", "issue_number", "title"}},
+		{MilestoneResult{}, []string{"error", "issue_number", "milestone", "status"}},
+		{MoveFailure{}, []string{"issue_number", "message", "status"}},
+		{MoveResult{}, []string{"epic_id", "error", "issue_id", "pipeline_id", "reason", "resolved", "rule", "status", "status_code", "verification"}},
+		{MovedPipeline{}, []string{"id", "index", "pipelines", "resolved"}},
+		{PipelineSummary{}, []string{"id", "issue_count", "name"}},
+		{PipelineView{}, []string{"id", "issues", "name"}},
+		{PlannedImportChange{}, []string{"from_estimate", "from_pipeline_id", "issue_number", "set_estimate", "to_estimate", "to_pipeline_id"}},
+		{ProjectSyncResult{}, []string{"error", "issue_number", "option", "project", "status"}},
+		{ResolvedIDs{}, []string{"pipeline_id", "repository_id", "workspace_id"}},
+		{UndoMove{}, []string{"from_pipeline_id", "from_position", "issue_number", "to_pipeline_id"}},
+		{UndoRecord{}, []string{"moves", "repository_id", "workspace_id"}},
+		{VerificationReport{}, []string{"issues", "verified"}},
+		{zenhub.Board{}, []string{"pipelines"}},
+		{zenhub.BoardIssue{}, []string{"estimate", "is_epic", "issue_number", "position"}},
+		{zenhub.Estimate{}, []string{"value"}},
+		{zenhub.Pipeline{}, []string{"id", "issues", "name"}},
+		{zenhub.Sprint{}, []string{"end_at", "id", "issues", "name", "start_at"}},
+		{zenhub.SprintEstimate{}, []string{"value"}},
+		{zenhub.SprintIssue{}, []string{"estimate", "number", "title"}},
+		{zenhub.Workspace{}, []string{"description", "id", "name", "repositories"}},
+	}
+
+	for _, test := range tests {
+		typ := reflect.TypeOf(test.value)
+		t.Run(typ.String(), func(t *testing.T) {
+			keys := jsonKeys(typ)
+			if !reflect.DeepEqual(keys, test.keys) {
+				t.Errorf("expected keys %v, got %v", test.keys, keys)
+			}
+			for _, key := range keys {
+				if !snakeCase.MatchString(key) {
+					t.Errorf("expected key %s to be snake_case", key)
+				}
+			}
+
+			// Keys left out when empty aren't in the encoded zero value,
+			// but every key that is should be expected.
+			body, err := json.Marshal(test.value)
+			if err != nil {
+				t.Fatalf("failed to marshal %T: %v", test.value, err)
+			}
+			var encoded map[string]interface{}
+			if err := json.Unmarshal(body, &encoded); err != nil {
+				t.Fatalf("failed to decode %s: %v", body, err)
+			}
+			for key := range encoded {
+				if i := sort.SearchStrings(test.keys, key); i == len(test.keys) || test.keys[i] != key {
+					t.Errorf("unexpected key %s in %s", key, body)
+				}
+			}
+		})
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	estimate := 3
+	value := struct {
+		Name     string                 `json:"name"`
+		Reserved []string               `json:"reserved"`
+		Estimate *int                   `json:"estimate"`
+		Missing  *int                   `json:"missing"`
+		Skipped  string                 `json:"skipped,omitempty"`
+		Ratio    float64                `json:"ratio"`
+		Nested   ExportedIssue          `json:"nested"`
+		Empty    map[string]interface{} `json:"empty"`
+	}{
+		Name:     "In Progress: QA",
+		Reserved: []string{"yes", "null", "42", ""},
+		Estimate: &estimate,
+		Ratio:    0.5,
+		Nested:   ExportedIssue{IssueNumber: 7, PipelineID: testPipelineID},
+		Empty:    map[string]interface{}{},
+	}
+
+	body, err := MarshalYAML(value)
+	if err != nil {
+		t.Fatalf("failed to marshal YAML: %v", err)
+	}
+
+	var decoded yaml.MapSlice
+	if err := yaml.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("failed to decode YAML %s: %v", body, err)
+	}
+	var keys []string
+	for _, item := range decoded {
+		keys = append(keys, item.Key.(string))
+	}
+	if want := []string{"name", "reserved", "estimate", "missing", "ratio", "nested", "empty"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected keys %v in JSON order, got %v", want, keys)
+	}
+
+	// Decoded back, the YAML should hold the same values as the JSON.
+	var fromYAML, fromJSON interface{}
+	if err := yaml.Unmarshal(body, &fromYAML); err != nil {
+		t.Fatalf("failed to decode YAML %s: %v", body, err)
+	}
+	jsonBody, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to marshal JSON: %v", err)
+	}
+	if err := yaml.Unmarshal(jsonBody, &fromJSON); err != nil {
+		t.Fatalf("failed to decode JSON %s: %v", jsonBody, err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("expected YAML %s to hold the values of JSON %s", body, jsonBody)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/urfave/cli/v2"
+)
+
+// MovedPipeline is a pipeline moved by pipeline move, with the pipelines of
+// the board in their new order.
+type MovedPipeline struct {
+	ID        string            `json:"id"`
+	Index     int               `json:"index"`
+	Pipelines []PipelineSummary `json:"pipelines"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// MovePipelineCommand moves a pipeline to a new (zero-based) index on the
+// board. Pipelines can only be moved through the GraphQL API.
+func MovePipelineCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("expected exactly two arguments, the pipeline ID and the new index. Received %d", ctx.Args().Len())
+	}
+
+	pipelineID := ctx.Args().First()
+
+	index, err := strconv.Atoi(ctx.Args().Get(1))
+	if err != nil {
+		return fmt.Errorf("expected new index to be an int, got %s", ctx.Args().Get(1))
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := RequireGraphQL(client, "pipeline move"); err != nil {
+		return err
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	if zenhub.NewBoardIndex(board).Pipeline(pipelineID) == nil {
+		return fmt.Errorf("pipeline %s not found in workspace %s", pipelineID, workspaceID)
+	}
+
+	if index < 0 || index >= len(board.Pipelines) {
+		return fmt.Errorf("expected new index to be between 0 and %d, got %d", len(board.Pipelines)-1, index)
+	}
+
+	if err := client.MovePipeline(pipelineID, index); err != nil {
+		return err
+	}
+
+	structured := IsStructuredOutput(ctx)
+	if IsQuiet(ctx) && !structured {
+		return nil
+	}
+
+	board, err = GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	if structured {
+		result := MovedPipeline{
+			ID:        pipelineID,
+			Index:     index,
+			Pipelines: make([]PipelineSummary, 0, len(board.Pipelines)),
+			Resolved: ResolvedIDs{
+				WorkspaceID:  workspaceID,
+				RepositoryID: repositoryID,
+				PipelineID:   pipelineID,
+			},
+		}
+		for _, pipeline := range board.Pipelines {
+			result.Pipelines = append(result.Pipelines, PipelineSummary{
+				ID:         pipeline.ID,
+				Name:       pipeline.Name,
+				IssueCount: len(pipeline.Issues),
+			})
+		}
+		return PrintStructured(result)
+	}
+
+	fmt.Printf("Successfully moved pipeline %s to index %d\n", pipelineID, index)
+	for i, pipeline := range board.Pipelines {
+		fmt.Printf("%d\t%s\t%s\n", i, pipeline.ID, pipeline.Name)
+	}
+
+	return nil
+}
+
+// DeletedPipeline is a pipeline deleted by pipeline delete, with the number
+// of issues it contained.
+type DeletedPipeline struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	IssueCount int    `json:"issue_count"`
+
+	Resolved ResolvedIDs `json:"resolved"`
+}
+
+// DeletePipelineCommand deletes a pipeline from the board.
+//
+// Non-empty pipelines are only deleted with the `force` flag, and the user is
+// asked to confirm unless the `yes` flag is set. Pipelines can only be
+// deleted through the GraphQL API.
+func DeletePipelineCommand(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one argument, the pipeline ID. Received %d", ctx.Args().Len())
+	}
+
+	pipelineID := ctx.Args().First()
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := RequireGraphQL(client, "pipeline delete"); err != nil {
+		return err
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	pipeline := zenhub.NewBoardIndex(board).Pipeline(pipelineID)
+	if pipeline == nil {
+		return fmt.Errorf("pipeline %s not found in workspace %s", pipelineID, workspaceID)
+	}
+
+	issues := len(pipeline.Issues)
+	if !IsQuiet(ctx) {
+		fmt.Fprintf(os.Stderr, "Pipeline %s (%s) contains %d issues\n", pipeline.Name, pipeline.ID, issues)
+	}
+	if issues > 0 && !ctx.Bool("force") {
+		return fmt.Errorf("refusing to delete non-empty pipeline %s, use --force to delete it anyway", pipeline.Name)
+	}
+
+	if !ctx.Bool("yes") {
+		ok, err := Confirm(fmt.Sprintf("Delete pipeline %s from workspace %s?", pipeline.Name, workspaceID))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("pipeline %s was not deleted", pipeline.Name)
+		}
+	}
+
+	if err := client.DeletePipeline(pipelineID); err != nil {
+		return err
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(DeletedPipeline{
+			ID:         pipeline.ID,
+			Name:       pipeline.Name,
+			IssueCount: issues,
+			Resolved: ResolvedIDs{
+				WorkspaceID:  workspaceID,
+				RepositoryID: repositoryID,
+				PipelineID:   pipeline.ID,
+			},
+		})
+	}
+	if !IsQuiet(ctx) {
+		fmt.Printf("Successfully deleted pipeline %s (%s)\n", pipeline.Name, pipeline.ID)
+	}
+
+	return nil
+}
+
+// PipelineSummary is a pipeline as listed by pipeline ls.
+type PipelineSummary struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	IssueCount int    `json:"issue_count"`
+}
+
+// ListPipelines returns the ID, name and number of issues of each pipeline
+// in the workspace, in board order.
+func ListPipelines(ctx *cli.Context) ([]PipelineSummary, error) {
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelines := make([]PipelineSummary, 0, len(board.Pipelines))
+	for _, pipeline := range board.Pipelines {
+		pipelines = append(pipelines, PipelineSummary{
+			ID:         pipeline.ID,
+			Name:       pipeline.Name,
+			IssueCount: len(pipeline.Issues),
+		})
+	}
+	return pipelines, nil
+}
+
+// ListPipelinesCommand lists the ID, name and number of issues of each
+// pipeline in the workspace, in board order unless sorted by the `sort` flag.
+func ListPipelinesCommand(ctx *cli.Context) error {
+	var key string
+	var descending bool
+	if value := ctx.String("sort"); value != "" {
+		var err error
+		if key, descending, err = ParseSort(value, PipelineSortKeys...); err != nil {
+			return err
+		}
+	}
+
+	pipelines, err := ListPipelines(ctx)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		SortPipelines(pipelines, key, descending)
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(pipelines)
+	}
+
+	for _, pipeline := range pipelines {
+		fmt.Printf("%s\t%s\t%d\n", pipeline.ID, pipeline.Name, pipeline.IssueCount)
+	}
+
+	return nil
+}
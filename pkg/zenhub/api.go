@@ -0,0 +1,470 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// API is the ZenHub API a client sends its requests to.
+type API string
+
+const (
+	// APIREST is ZenHub's REST API, the `/p1` and `/p2` endpoints.
+	APIREST API = "rest"
+
+	// APIGraphQL is ZenHub's public GraphQL API. Operations the REST API
+	// supports are translated into GraphQL queries and mutations.
+	APIGraphQL API = "graphql"
+)
+
+// graphQLTokenPrefix is the prefix of the API keys ZenHub issues for its
+// GraphQL API. REST API tokens have no prefix.
+const graphQLTokenPrefix = "zh_"
+
+// DetectAPI returns the API the given token is for, going by its prefix.
+func DetectAPI(token string) API {
+	if strings.HasPrefix(token, graphQLTokenPrefix) {
+		return APIGraphQL
+	}
+	return APIREST
+}
+
+// ErrUnsupportedByAPI is returned by operations the client's API has no
+// equivalent for.
+var ErrUnsupportedByAPI = errors.New("not supported by this API")
+
+// ErrRequiresGraphQL is returned by operations only the GraphQL API supports
+// when the client sends its requests to another API.
+var ErrRequiresGraphQL = errors.New("requires a GraphQL API key (zh_…)")
+
+// requireGraphQL returns the error for an operation only the GraphQL API
+// supports, or nil if the client sends its requests to it.
+func (c *Client) requireGraphQL(operation string) error {
+	if c.api == APIGraphQL {
+		return nil
+	}
+	return fmt.Errorf("failed to %s: %w (using the %s API)", operation, ErrRequiresGraphQL, c.api)
+}
+
+// unsupported returns the error for an operation the client's API doesn't
+// support.
+func (c *Client) unsupported(operation string) error {
+	return fmt.Errorf("failed to %s: %w (%s), use the REST API instead", operation, ErrUnsupportedByAPI, c.api)
+}
+
+// graphQLEstimate is an estimate as returned by the GraphQL API, where
+// values can be fractional.
+type graphQLEstimate struct {
+	Value float64 `json:"value"`
+}
+
+// estimate converts the estimate to the REST API's representation, or nil
+// if there is none. Fractional values have no such representation, so they
+// are an error rather than being truncated.
+func (e *graphQLEstimate) estimate() (*Estimate, error) {
+	if e == nil {
+		return nil, nil
+	}
+	if e.Value != math.Trunc(e.Value) {
+		return nil, fmt.Errorf("fractional estimate %g is not supported", e.Value)
+	}
+	return &Estimate{Value: int(e.Value)}, nil
+}
+
+// graphQLPageInfo is the position of a page of a GraphQL connection.
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// graphQLPageSize is the number of nodes requested per page of a GraphQL
+// connection, the most the API allows.
+const graphQLPageSize = 100
+
+// graphQLBoardIssues is a page of a pipeline's issues as returned by
+// `boardQuery` and `pipelineIssuesQuery`.
+type graphQLBoardIssues struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		Number   int              `json:"number"`
+		Estimate *graphQLEstimate `json:"estimate"`
+	} `json:"nodes"`
+}
+
+// boardQuery is the GraphQL query used to get a page of a workspace's
+// board, limited to the issues of one repository. Pipelines with more
+// issues than fit on the first page are paged through with
+// `pipelineIssuesQuery`.
+const boardQuery = `query Board($workspaceId: ID!, $repositoryGhId: Int!, $first: Int!, $after: String) {
+  workspace(id: $workspaceId) {
+    pipelinesConnection(first: $first, after: $after) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+      nodes {
+        id
+        name
+        issues(first: $first, repositoryGhIds: [$repositoryGhId]) {
+          pageInfo {
+            hasNextPage
+            endCursor
+          }
+          nodes {
+            number
+            estimate {
+              value
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// pipelineIssuesQuery is the GraphQL query used to get the pages of a
+// pipeline's issues after the first.
+const pipelineIssuesQuery = `query PipelineIssues($pipelineId: ID!, $repositoryGhId: Int!, $first: Int!, $after: String) {
+  node(id: $pipelineId) {
+    ... on Pipeline {
+      issues(first: $first, after: $after, repositoryGhIds: [$repositoryGhId]) {
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+        nodes {
+          number
+          estimate {
+            value
+          }
+        }
+      }
+    }
+  }
+}`
+
+// getBoardGraphQL fetches the board like `getBoard`, through the GraphQL
+// API, paging through all of its pipelines and their issues. Whether issues
+// are epics isn't reported.
+func (c *Client) getBoardGraphQL(workspaceID string, repositoryID uint) (*Board, error) {
+	board := &Board{Pipelines: []Pipeline{}}
+	after := ""
+	for {
+		var result struct {
+			Workspace struct {
+				PipelinesConnection struct {
+					PageInfo graphQLPageInfo `json:"pageInfo"`
+					Nodes    []struct {
+						ID     string             `json:"id"`
+						Name   string             `json:"name"`
+						Issues graphQLBoardIssues `json:"issues"`
+					} `json:"nodes"`
+				} `json:"pipelinesConnection"`
+			} `json:"workspace"`
+		}
+		variables := map[string]interface{}{
+			"workspaceId":    workspaceID,
+			"repositoryGhId": repositoryID,
+			"first":          graphQLPageSize,
+		}
+		if after != "" {
+			variables["after"] = after
+		}
+		if err := c.GraphQL(boardQuery, variables, &result); err != nil {
+			return nil, fmt.Errorf("failed to get board: %w", err)
+		}
+
+		for _, node := range result.Workspace.PipelinesConnection.Nodes {
+			pipeline := Pipeline{
+				ID:     node.ID,
+				Name:   node.Name,
+				Issues: make([]BoardIssue, 0, len(node.Issues.Nodes)),
+			}
+			issues := node.Issues
+			for {
+				for _, issue := range issues.Nodes {
+					estimate, err := issue.Estimate.estimate()
+					if err != nil {
+						return nil, fmt.Errorf("failed to get board: issue %d: %w", issue.Number, err)
+					}
+					pipeline.Issues = append(pipeline.Issues, BoardIssue{
+						IssueNumber: issue.Number,
+						Estimate:    estimate,
+						Position:    len(pipeline.Issues),
+					})
+				}
+				if !issues.PageInfo.HasNextPage {
+					break
+				}
+				next, err := c.getPipelineIssuesGraphQL(node.ID, repositoryID, issues.PageInfo.EndCursor)
+				if err != nil {
+					return nil, err
+				}
+				issues = *next
+			}
+			board.Pipelines = append(board.Pipelines, pipeline)
+		}
+
+		pageInfo := result.Workspace.PipelinesConnection.PageInfo
+		if !pageInfo.HasNextPage {
+			return board, nil
+		}
+		if pageInfo.EndCursor == "" || pageInfo.EndCursor == after {
+			return nil, fmt.Errorf("failed to get board: GraphQL API reported more pipelines without a new cursor")
+		}
+		after = pageInfo.EndCursor
+	}
+}
+
+// getPipelineIssuesGraphQL fetches the page of the pipeline's issues in the
+// given repository after the cursor `after`.
+func (c *Client) getPipelineIssuesGraphQL(pipelineID string, repositoryID uint, after string) (*graphQLBoardIssues, error) {
+	if after == "" {
+		return nil, fmt.Errorf("failed to get issues of pipeline %s: GraphQL API reported more issues without a cursor", pipelineID)
+	}
+
+	var result struct {
+		Node *struct {
+			Issues graphQLBoardIssues `json:"issues"`
+		} `json:"node"`
+	}
+	variables := map[string]interface{}{
+		"pipelineId":     pipelineID,
+		"repositoryGhId": repositoryID,
+		"first":          graphQLPageSize,
+		"after":          after,
+	}
+	if err := c.GraphQL(pipelineIssuesQuery, variables, &result); err != nil {
+		return nil, fmt.Errorf("failed to get issues of pipeline %s: %w", pipelineID, err)
+	}
+	if result.Node == nil {
+		return nil, fmt.Errorf("failed to get issues of pipeline %s: pipeline not found", pipelineID)
+	}
+	if result.Node.Issues.PageInfo.HasNextPage && result.Node.Issues.PageInfo.EndCursor == after {
+		return nil, fmt.Errorf("failed to get issues of pipeline %s: GraphQL API reported more issues without a new cursor", pipelineID)
+	}
+	return &result.Node.Issues, nil
+}
+
+// getBoardJSONGraphQL fetches the board like `GetBoardJSON`, through the
+// GraphQL API, encoded in the REST API's format.
+func (c *Client) getBoardJSONGraphQL(workspaceID string, repositoryID uint) ([]byte, error) {
+	board, err := c.getBoardGraphQL(workspaceID, repositoryID)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(board)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode board: %w", err)
+	}
+	return body, nil
+}
+
+// issueQuery is the GraphQL query used to get an issue by its repository
+// and number.
+const issueQuery = `query Issue($repositoryGhId: Int!, $issueNumber: Int!) {
+  issueByInfo(repositoryGhId: $repositoryGhId, issueNumber: $issueNumber) {
+    id
+    estimate {
+      value
+    }
+    pipelineIssues(first: 1) {
+      nodes {
+        pipeline {
+          id
+          name
+          workspace {
+            id
+          }
+        }
+      }
+    }
+  }
+}`
+
+// graphQLIssue is an issue as returned by `issueQuery`.
+type graphQLIssue struct {
+	ID             string           `json:"id"`
+	Estimate       *graphQLEstimate `json:"estimate"`
+	PipelineIssues struct {
+		Nodes []struct {
+			Pipeline struct {
+				ID        string `json:"id"`
+				Name      string `json:"name"`
+				Workspace struct {
+					ID string `json:"id"`
+				} `json:"workspace"`
+			} `json:"pipeline"`
+		} `json:"nodes"`
+	} `json:"pipelineIssues"`
+}
+
+// getGraphQLIssue fetches the given issue from the GraphQL API, which
+// identifies issues by an ID of its own rather than their number.
+func (c *Client) getGraphQLIssue(repositoryID uint, issueID int) (*graphQLIssue, error) {
+	var result struct {
+		IssueByInfo *graphQLIssue `json:"issueByInfo"`
+	}
+	variables := map[string]interface{}{
+		"repositoryGhId": repositoryID,
+		"issueNumber":    issueID,
+	}
+	if err := c.GraphQL(issueQuery, variables, &result); err != nil {
+		return nil, fmt.Errorf("failed to get issue %d: %w", issueID, err)
+	}
+	if result.IssueByInfo == nil || result.IssueByInfo.ID == "" {
+		return nil, fmt.Errorf("failed to get issue %d: not found in repository %d", issueID, repositoryID)
+	}
+	return result.IssueByInfo, nil
+}
+
+// getIssueDataGraphQL fetches the issue's data like `GetIssueData`, through
+// the GraphQL API. Whether the issue is an epic isn't reported.
+func (c *Client) getIssueDataGraphQL(repositoryID uint, issueID int) (*IssueData, error) {
+	issue, err := c.getGraphQLIssue(repositoryID, issueID)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate, err := issue.Estimate.estimate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue %d: %w", issueID, err)
+	}
+
+	data := &IssueData{Estimate: estimate}
+	if nodes := issue.PipelineIssues.Nodes; len(nodes) > 0 {
+		data.Pipeline = IssuePipeline{
+			Name:        nodes[0].Pipeline.Name,
+			PipelineID:  nodes[0].Pipeline.ID,
+			WorkspaceID: nodes[0].Pipeline.Workspace.ID,
+		}
+	}
+	return data, nil
+}
+
+// moveIssueMutation is the GraphQL mutation used to move an issue. Without
+// a position the issue is put at the bottom of the pipeline.
+const moveIssueMutation = `mutation MoveIssue($issueId: ID!, $pipelineId: ID!, $position: Int) {
+  moveIssue(input: {issueId: $issueId, pipelineId: $pipelineId, position: $position}) {
+    issue {
+      id
+    }
+  }
+}`
+
+// moveIssueGraphQL moves the issue like `MoveIssue`, through the GraphQL API.
+func (c *Client) moveIssueGraphQL(repositoryID uint, issueID int, request MoveIssueRequest) error {
+	issue, err := c.getGraphQLIssue(repositoryID, issueID)
+	if err != nil {
+		return fmt.Errorf("failed to move issue between pipelines: %w", err)
+	}
+
+	variables := map[string]interface{}{
+		"issueId":    issue.ID,
+		"pipelineId": request.PipelineID,
+	}
+	switch request.Position {
+	case "top":
+		variables["position"] = 0
+	case "bottom", "":
+	default:
+		index, err := strconv.Atoi(string(request.Position))
+		if err != nil {
+			return fmt.Errorf("failed to move issue between pipelines: invalid position %s", request.Position)
+		}
+		variables["position"] = index
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"issue_id":    issueID,
+		"pipeline_id": request.PipelineID,
+	}).Debug("Sending move issue mutation")
+	if err := c.GraphQL(moveIssueMutation, variables, nil); err != nil {
+		return fmt.Errorf("failed to move issue between pipelines: %w", err)
+	}
+	return nil
+}
+
+// setEstimateMutation is the GraphQL mutation used to set or, with a null
+// value, clear an issue's estimate.
+const setEstimateMutation = `mutation SetEstimate($issueId: ID!, $value: Float) {
+  setEstimate(input: {issueId: $issueId, value: $value}) {
+    issue {
+      id
+    }
+  }
+}`
+
+// setEstimateGraphQL sets the issue's estimate like `SetEstimate`, or clears
+// it if `estimate` is nil, through the GraphQL API.
+func (c *Client) setEstimateGraphQL(repositoryID uint, issueID int, estimate *int) error {
+	issue, err := c.getGraphQLIssue(repositoryID, issueID)
+	if err != nil {
+		return err
+	}
+
+	variables := map[string]interface{}{
+		"issueId": issue.ID,
+		"value":   nil,
+	}
+	if estimate != nil {
+		variables["value"] = *estimate
+	}
+	return c.GraphQL(setEstimateMutation, variables, nil)
+}
+
+// repositoryWorkspacesQuery is the GraphQL query used to list the
+// workspaces a repository belongs to.
+const repositoryWorkspacesQuery = `query RepositoryWorkspaces($repositoryGhId: Int!) {
+  repositoriesByGhId(ghIds: [$repositoryGhId]) {
+    workspacesConnection(first: 100) {
+      nodes {
+        id
+        name
+        description
+      }
+    }
+  }
+}`
+
+// getWorkspacesGraphQL fetches the repository's workspaces like
+// `GetWorkspaces`, through the GraphQL API. Each workspace's repositories
+// are limited to the given one.
+func (c *Client) getWorkspacesGraphQL(repositoryID uint) ([]Workspace, error) {
+	var result struct {
+		RepositoriesByGhID []struct {
+			WorkspacesConnection struct {
+				Nodes []struct {
+					ID          string `json:"id"`
+					Name        string `json:"name"`
+					Description string `json:"description"`
+				} `json:"nodes"`
+			} `json:"workspacesConnection"`
+		} `json:"repositoriesByGhId"`
+	}
+	variables := map[string]interface{}{
+		"repositoryGhId": repositoryID,
+	}
+	if err := c.GraphQL(repositoryWorkspacesQuery, variables, &result); err != nil {
+		return nil, fmt.Errorf("failed to get workspaces: %w", err)
+	}
+
+	workspaces := []Workspace{}
+	for _, repository := range result.RepositoriesByGhID {
+		for _, node := range repository.WorkspacesConnection.Nodes {
+			workspaces = append(workspaces, Workspace{
+				ID:           node.ID,
+				Name:         node.Name,
+				Description:  node.Description,
+				Repositories: []uint{repositoryID},
+			})
+		}
+	}
+	return workspaces, nil
+}
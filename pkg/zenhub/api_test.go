@@ -0,0 +1,149 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// graphQLServer answers GraphQL requests with the response `respond` returns
+// for the query's operation name and variables.
+func graphQLServer(t *testing.T, respond func(operation string, variables map[string]interface{}) string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != GraphQLPath {
+			t.Errorf("expected a request to %s, got %s", GraphQLPath, r.URL.Path)
+		}
+		var req GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode GraphQL request: %v", err)
+		}
+		operation := strings.Fields(strings.SplitN(req.Query, "(", 2)[0])[1]
+		w.Write([]byte(respond(operation, req.Variables)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetBoardGraphQLPaginates(t *testing.T) {
+	server := graphQLServer(t, func(operation string, variables map[string]interface{}) string {
+		switch {
+		case operation == "Board" && variables["after"] == nil:
+			return `{"data": {"workspace": {"pipelinesConnection": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "p1"},
+				"nodes": [{"id": "new", "name": "New Issues", "issues": {
+					"pageInfo": {"hasNextPage": true, "endCursor": "i1"},
+					"nodes": [{"number": 1, "estimate": {"value": 3}}]
+				}}]
+			}}}}`
+		case operation == "Board" && variables["after"] == "p1":
+			return `{"data": {"workspace": {"pipelinesConnection": {
+				"pageInfo": {"hasNextPage": false, "endCursor": "p2"},
+				"nodes": [{"id": "done", "name": "Done", "issues": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": [{"number": 3, "estimate": null}]
+				}}]
+			}}}}`
+		case operation == "PipelineIssues" && variables["pipelineId"] == "new" && variables["after"] == "i1":
+			return `{"data": {"node": {"issues": {
+				"pageInfo": {"hasNextPage": false, "endCursor": "i2"},
+				"nodes": [{"number": 2, "estimate": null}]
+			}}}}`
+		}
+		t.Errorf("unexpected %s query with variables %v", operation, variables)
+		return `{"errors": [{"message": "unexpected query"}]}`
+	})
+
+	client := NewClient(server.URL, "zh_token").WithAPI(APIGraphQL)
+	board, err := client.GetBoard("workspace", 1, false)
+	if err != nil {
+		t.Fatalf("failed to get board: %v", err)
+	}
+
+	want := []Pipeline{
+		{ID: "new", Name: "New Issues", Issues: []BoardIssue{
+			{IssueNumber: 1, Estimate: &Estimate{Value: 3}, Position: 0},
+			{IssueNumber: 2, Position: 1},
+		}},
+		{ID: "done", Name: "Done", Issues: []BoardIssue{
+			{IssueNumber: 3, Position: 0},
+		}},
+	}
+	if !reflect.DeepEqual(board.Pipelines, want) {
+		t.Errorf("expected pipelines %+v, got %+v", want, board.Pipelines)
+	}
+}
+
+func TestGetBoardGraphQLRejectsRepeatedCursor(t *testing.T) {
+	server := graphQLServer(t, func(operation string, variables map[string]interface{}) string {
+		return `{"data": {"workspace": {"pipelinesConnection": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "p1"},
+			"nodes": []
+		}}}}`
+	})
+
+	client := NewClient(server.URL, "zh_token").WithAPI(APIGraphQL)
+	if _, err := client.GetBoard("workspace", 1, false); err == nil {
+		t.Fatal("expected an error for a cursor that doesn't advance")
+	}
+}
+
+func TestGraphQLEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		estimate *graphQLEstimate
+		want     *Estimate
+		wantErr  bool
+	}{
+		{name: "none"},
+		{name: "whole", estimate: &graphQLEstimate{Value: 5}, want: &Estimate{Value: 5}},
+		{name: "zero", estimate: &graphQLEstimate{Value: 0}, want: &Estimate{Value: 0}},
+		{name: "fractional", estimate: &graphQLEstimate{Value: 0.5}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.estimate.estimate()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got: %v", test.wantErr, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGraphQLOnlyOperations(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL, "token").WithAPI(APIREST)
+
+	operations := map[string]func() error{
+		"move pipeline":   func() error { return client.MovePipeline("p1", 0) },
+		"create pipeline": func() error { _, err := client.CreatePipeline("ws1", "New"); return err },
+		"delete pipeline": func() error { return client.DeletePipeline("p1") },
+		"get sprints":     func() error { _, err := client.GetSprints("ws1"); return err },
+		"get estimate values": func() error {
+			_, err := client.GetEstimateValues("ws1")
+			return err
+		},
+	}
+	for name, operation := range operations {
+		err := operation()
+		if !errors.Is(err, ErrRequiresGraphQL) || !strings.Contains(err.Error(), "failed to "+name) {
+			t.Errorf("%s: expected an error requiring the GraphQL API, got: %v", name, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests to be sent, got %d", requests)
+	}
+}
@@ -0,0 +1,491 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Board is the response body of a request to get a workspace's board.
+type Board struct {
+	Pipelines []Pipeline `json:"pipelines"`
+}
+
+// Pipeline is a single pipeline (column) on a board.
+type Pipeline struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Issues []BoardIssue `json:"issues"`
+}
+
+// BoardIssue is an issue as it appears in a board pipeline.
+type BoardIssue struct {
+	IssueNumber int       `json:"issue_number"`
+	Estimate    *Estimate `json:"estimate,omitempty"`
+	Position    int       `json:"position"`
+	IsEpic      bool      `json:"is_epic"`
+}
+
+// Estimate is the estimate of an issue.
+type Estimate struct {
+	Value int `json:"value"`
+}
+
+// BoardTruncationRetries is the number of times a truncated board is
+// re-fetched when retrying on truncation is enabled.
+var BoardTruncationRetries int = 2
+
+// TruncatedBoardError is returned when the board response ends before the
+// whole board has been read.
+type TruncatedBoardError struct {
+	// Pipelines is the number of pipelines successfully parsed before the
+	// response was cut off.
+	Pipelines int
+	Err       error
+}
+
+func (e *TruncatedBoardError) Error() string {
+	return fmt.Sprintf("board response was truncated after %d pipelines: %s", e.Pipelines, e.Err)
+}
+
+func (e *TruncatedBoardError) Unwrap() error {
+	return e.Err
+}
+
+// boardURL returns the URL of the board endpoint of the given workspace and
+// repository.
+func (c *Client) boardURL(workspaceID string, repositoryID uint) string {
+	return c.url("/p2/workspaces/%s/repositories/%d/board", workspaceID, repositoryID)
+}
+
+// GetBoard fetches the board of the given workspace and repository.
+//
+// If `retryOnTruncation` is set, a truncated response is re-fetched up to
+// `BoardTruncationRetries` times before giving up.
+func (c *Client) GetBoard(workspaceID string, repositoryID uint, retryOnTruncation bool) (*Board, error) {
+	attempts := 1
+	if retryOnTruncation {
+		attempts += BoardTruncationRetries
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var board *Board
+		board, err = c.getBoard(workspaceID, repositoryID)
+		var truncatedErr *TruncatedBoardError
+		if !errors.As(err, &truncatedErr) {
+			return board, err
+		}
+		logrus.WithFields(logrus.Fields{
+			"attempt": attempt,
+			"error":   err,
+		}).Warn("Board response was truncated")
+	}
+
+	return nil, err
+}
+
+func (c *Client) getBoard(workspaceID string, repositoryID uint) (*Board, error) {
+	if c.api == APIGraphQL {
+		return c.getBoardGraphQL(workspaceID, repositoryID)
+	}
+
+	resp, err := c.send(http.MethodGet, c.boardURL(workspaceID, repositoryID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get board: %w", err)
+	}
+	defer resp.Body.Close()
+
+	board, err := DecodeBoard(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode board response: %w", err)
+	}
+
+	return board, nil
+}
+
+// GetBoardJSON fetches the board of the given workspace and repository as
+// the raw JSON returned by the API.
+func (c *Client) GetBoardJSON(workspaceID string, repositoryID uint) ([]byte, error) {
+	if c.api == APIGraphQL {
+		return c.getBoardJSONGraphQL(workspaceID, repositoryID)
+	}
+
+	resp, err := c.send(http.MethodGet, c.boardURL(workspaceID, repositoryID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get board: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body of board response: %w", err)
+	}
+
+	return body, nil
+}
+
+// DecodeBoard decodes a board, one pipeline at a time, from the given
+// reader.
+//
+// Large boards can be slow to stream so decoding pipelines as they arrive
+// lets us report how far we got if the response is cut off.
+func DecodeBoard(r io.Reader) (*Board, error) {
+	decoder := json.NewDecoder(r)
+	board := &Board{}
+
+	wrap := func(err error) error {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return &TruncatedBoardError{Pipelines: len(board.Pipelines), Err: io.ErrUnexpectedEOF}
+		}
+		return fmt.Errorf("failed after %d pipelines: %w", len(board.Pipelines), err)
+	}
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, wrap(err)
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		if key != "pipelines" {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return nil, wrap(err)
+			}
+			continue
+		}
+
+		if err := expectDelim(decoder, '['); err != nil {
+			return nil, wrap(err)
+		}
+		for decoder.More() {
+			var pipeline Pipeline
+			if err := decoder.Decode(&pipeline); err != nil {
+				return nil, wrap(err)
+			}
+			board.Pipelines = append(board.Pipelines, pipeline)
+		}
+		if err := expectDelim(decoder, ']'); err != nil {
+			return nil, wrap(err)
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
+		return nil, wrap(err)
+	}
+
+	return board, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %s, got %v", delim, token)
+	}
+	return nil
+}
+
+// BoardIndex indexes a board so pipelines and issues can be looked up
+// without scanning it.
+//
+// Build it once per command with `NewBoardIndex` and share it between the
+// checks that need it.
+type BoardIndex struct {
+	Board *Board
+
+	// pipelines is the index of each pipeline in `Board.Pipelines`, by ID.
+	pipelines map[string]int
+	issues    map[int]boardIssueLocation
+}
+
+// boardIssueLocation is where an issue is on the board.
+type boardIssueLocation struct {
+	pipelineID string
+	index      int
+}
+
+// NewBoardIndex builds an index of the given board.
+func NewBoardIndex(board *Board) *BoardIndex {
+	index := &BoardIndex{
+		Board:     board,
+		pipelines: make(map[string]int, len(board.Pipelines)),
+		issues:    make(map[int]boardIssueLocation),
+	}
+	index.reindexPipelines(0)
+	for i := range board.Pipelines {
+		index.reindexIssues(&board.Pipelines[i], 0)
+	}
+	return index
+}
+
+// reindexPipelines updates the index of the pipelines on the board from the
+// given index onwards, after pipelines before them were added.
+func (idx *BoardIndex) reindexPipelines(from int) {
+	for i := from; i < len(idx.Board.Pipelines); i++ {
+		idx.pipelines[idx.Board.Pipelines[i].ID] = i
+	}
+}
+
+// reindexIssues updates the locations of the pipeline's issues from the
+// given index onwards, after issues before them were added or removed.
+func (idx *BoardIndex) reindexIssues(pipeline *Pipeline, from int) {
+	for i := from; i < len(pipeline.Issues); i++ {
+		idx.issues[pipeline.Issues[i].IssueNumber] = boardIssueLocation{pipelineID: pipeline.ID, index: i}
+	}
+}
+
+// positionIndex returns the index the given position is at in a list of
+// `length` items: 0 for top, `length` for bottom, and an index clamped to
+// that range otherwise.
+func positionIndex(position MovePosition, length int) int {
+	switch position {
+	case "top":
+		return 0
+	case "bottom", "":
+		return length
+	}
+	index, err := strconv.Atoi(string(position))
+	if err != nil || index > length {
+		return length
+	}
+	if index < 0 {
+		return 0
+	}
+	return index
+}
+
+// AddPipeline adds a pipeline to the board at the given position, e.g. after
+// it has been created. Only the pipelines after it are reindexed.
+func (idx *BoardIndex) AddPipeline(pipeline Pipeline, position MovePosition) {
+	at := positionIndex(position, len(idx.Board.Pipelines))
+	pipelines := append(idx.Board.Pipelines, Pipeline{})
+	copy(pipelines[at+1:], pipelines[at:])
+	pipelines[at] = pipeline
+	idx.Board.Pipelines = pipelines
+
+	idx.reindexPipelines(at)
+	idx.reindexIssues(&idx.Board.Pipelines[at], 0)
+}
+
+// MoveIssue moves the issue with the given number to the given position in
+// the pipeline with the given ID, e.g. after it has been moved on ZenHub, so
+// later checks see the board as it is now. An issue not on the board is
+// added to the pipeline.
+//
+// Only the issues after the ones removed and inserted are reindexed, so
+// moving an issue to the bottom of a pipeline doesn't cost more with the size
+// of the board.
+func (idx *BoardIndex) MoveIssue(issueNumber int, pipelineID string, position MovePosition) {
+	target := idx.Pipeline(pipelineID)
+	if target == nil {
+		return
+	}
+
+	issue := BoardIssue{IssueNumber: issueNumber}
+	if location, ok := idx.issues[issueNumber]; ok {
+		source := idx.Pipeline(location.pipelineID)
+		issue = source.Issues[location.index]
+		source.Issues = append(source.Issues[:location.index], source.Issues[location.index+1:]...)
+		idx.reindexIssues(source, location.index)
+	}
+
+	at := positionIndex(position, len(target.Issues))
+	target.Issues = append(target.Issues, BoardIssue{})
+	copy(target.Issues[at+1:], target.Issues[at:])
+	target.Issues[at] = issue
+	idx.reindexIssues(target, at)
+}
+
+// Pipeline returns the pipeline with the given ID, or nil if there is no
+// such pipeline.
+func (idx *BoardIndex) Pipeline(pipelineID string) *Pipeline {
+	i, ok := idx.pipelines[pipelineID]
+	if !ok {
+		return nil
+	}
+	return &idx.Board.Pipelines[i]
+}
+
+// ErrPipelineNotFound is returned when no pipeline on the board has the
+// given ID or name.
+var ErrPipelineNotFound = errors.New("pipeline not found on the board")
+
+// ResolvePipelineID returns the ID of the pipeline with the given ID or,
+// failing that, name. Names are matched ignoring case and it is an error for
+// several pipelines to share the name.
+func (idx *BoardIndex) ResolvePipelineID(target string) (string, error) {
+	if idx.Pipeline(target) != nil {
+		return target, nil
+	}
+	return idx.ResolvePipelineName(target)
+}
+
+// ResolvePipelineName returns the ID of the pipeline with the given name,
+// matched ignoring case. It is an error for several pipelines to share the
+// name.
+func (idx *BoardIndex) ResolvePipelineName(name string) (string, error) {
+	var matches []string
+	for _, pipeline := range idx.Board.Pipelines {
+		if strings.EqualFold(pipeline.Name, name) {
+			matches = append(matches, pipeline.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s, expected one of %s", ErrPipelineNotFound, name, pipelineNames(idx.Board.Pipelines))
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("pipeline name %s is ambiguous, use one of the pipeline IDs: %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// MatchPipelineID returns the ID of the pipeline with the given ID or name
+// like `ResolvePipelineID`, falling back to fuzzy matching the name when
+// nothing matches exactly. Fuzzy matching ignores case, spaces and
+// punctuation, and accepts a prefix of the name or, failing that, any part
+// of it, so "in progress", "inprogress" and "prog" all match "In Progress".
+//
+// It is an error for the target to fuzzy match several pipelines.
+func (idx *BoardIndex) MatchPipelineID(target string) (string, error) {
+	pipelineID, err := idx.ResolvePipelineID(target)
+	if !errors.Is(err, ErrPipelineNotFound) {
+		return pipelineID, err
+	}
+
+	normalized := normalizePipelineName(target)
+	if normalized == "" {
+		return "", err
+	}
+
+	var prefixMatches, partialMatches []Pipeline
+	for _, pipeline := range idx.Board.Pipelines {
+		name := normalizePipelineName(pipeline.Name)
+		switch {
+		case name == normalized:
+			return pipeline.ID, nil
+		case strings.HasPrefix(name, normalized):
+			prefixMatches = append(prefixMatches, pipeline)
+		case strings.Contains(name, normalized):
+			partialMatches = append(partialMatches, pipeline)
+		}
+	}
+
+	matches := prefixMatches
+	if len(matches) == 0 {
+		matches = partialMatches
+	}
+	switch len(matches) {
+	case 0:
+		return "", err
+	case 1:
+		logrus.WithFields(logrus.Fields{
+			"target":      target,
+			"pipeline":    matches[0].Name,
+			"pipeline_id": matches[0].ID,
+		}).Debug("Fuzzy matched pipeline name")
+		return matches[0].ID, nil
+	default:
+		return "", fmt.Errorf("pipeline name %s is ambiguous, it matches %s", target, pipelineNames(matches))
+	}
+}
+
+// normalizePipelineName lowercases the given pipeline name and strips
+// everything but letters and digits from it, for fuzzy matching.
+func normalizePipelineName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// pipelineNames formats the names of the given pipelines as a quoted, comma
+// separated list for error messages.
+func pipelineNames(pipelines []Pipeline) string {
+	names := make([]string, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		names = append(names, strconv.Quote(pipeline.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// pipelineIDPattern matches ZenHub pipeline IDs, either from the REST API
+// (24 hex digits) or the GraphQL API (base64 encoded global IDs).
+var pipelineIDPattern = regexp.MustCompile(`^([0-9a-f]{24}|Z2lkOi8v[A-Za-z0-9+/=]+)$`)
+
+// LooksLikePipelineID reports whether the given pipeline argument is a raw
+// pipeline ID, rather than a name that needs looking up on the board.
+func LooksLikePipelineID(arg string) bool {
+	return pipelineIDPattern.MatchString(arg)
+}
+
+// PipelineIssues returns the issues in the pipeline with the given ID.
+func (idx *BoardIndex) PipelineIssues(pipelineID string) []BoardIssue {
+	if pipeline := idx.Pipeline(pipelineID); pipeline != nil {
+		return pipeline.Issues
+	}
+	return nil
+}
+
+// Issue returns the pipeline containing the issue with the given number and
+// the issue itself, or nil if the issue is not on the board.
+func (idx *BoardIndex) Issue(issueNumber int) (*Pipeline, *BoardIssue) {
+	location, ok := idx.issues[issueNumber]
+	if !ok {
+		return nil, nil
+	}
+	pipeline := idx.Pipeline(location.pipelineID)
+	return pipeline, &pipeline.Issues[location.index]
+}
+
+// IssuePosition is where an issue sits on the board.
+type IssuePosition struct {
+	IssueNumber  int    `json:"issue_number"`
+	PipelineID   string `json:"pipeline_id"`
+	PipelineName string `json:"pipeline_name"`
+	Index        int    `json:"index"`
+}
+
+// IssuePosition returns the pipeline the issue with the given number is in
+// and its zero-based index within that pipeline.
+func (idx *BoardIndex) IssuePosition(issueNumber int) (*IssuePosition, error) {
+	location, ok := idx.issues[issueNumber]
+	if !ok {
+		return nil, fmt.Errorf("issue %d not found on the board", issueNumber)
+	}
+	pipeline := idx.Pipeline(location.pipelineID)
+	return &IssuePosition{
+		IssueNumber:  issueNumber,
+		PipelineID:   pipeline.ID,
+		PipelineName: pipeline.Name,
+		Index:        location.index,
+	}, nil
+}
+
+// EstimateTotal returns the sum of the estimates of the issues in the
+// pipeline.
+func (p *Pipeline) EstimateTotal() int {
+	total := 0
+	for _, issue := range p.Issues {
+		if issue.Estimate != nil {
+			total += issue.Estimate.Value
+		}
+	}
+	return total
+}
@@ -0,0 +1,151 @@
+package zenhub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testBoard() *Board {
+	return &Board{Pipelines: []Pipeline{
+		{ID: "new", Name: "New Issues", Issues: []BoardIssue{{IssueNumber: 1}, {IssueNumber: 2}, {IssueNumber: 3}}},
+		{ID: "done", Name: "Done", Issues: []BoardIssue{{IssueNumber: 4}}},
+	}}
+}
+
+// issueNumbers returns the numbers of the issues in each pipeline of the
+// board, by pipeline ID.
+func issueNumbers(board *Board) map[string][]int {
+	numbers := make(map[string][]int, len(board.Pipelines))
+	for _, pipeline := range board.Pipelines {
+		numbers[pipeline.ID] = []int{}
+		for _, issue := range pipeline.Issues {
+			numbers[pipeline.ID] = append(numbers[pipeline.ID], issue.IssueNumber)
+		}
+	}
+	return numbers
+}
+
+// checkIndex checks every issue's indexed position matches where it is on
+// the board.
+func checkIndex(t *testing.T, index *BoardIndex) {
+	t.Helper()
+	for _, pipeline := range index.Board.Pipelines {
+		if got := index.Pipeline(pipeline.ID); got == nil || got.ID != pipeline.ID {
+			t.Errorf("expected pipeline %s to be indexed, got %+v", pipeline.ID, got)
+		}
+		for i, issue := range pipeline.Issues {
+			position, err := index.IssuePosition(issue.IssueNumber)
+			if err != nil {
+				t.Fatalf("expected issue %d to be indexed: %v", issue.IssueNumber, err)
+			}
+			if position.PipelineID != pipeline.ID || position.Index != i {
+				t.Errorf("expected issue %d at %s[%d], indexed at %s[%d]",
+					issue.IssueNumber, pipeline.ID, i, position.PipelineID, position.Index)
+			}
+		}
+	}
+}
+
+func TestBoardIndexMoveIssue(t *testing.T) {
+	tests := []struct {
+		name        string
+		issueNumber int
+		pipelineID  string
+		position    MovePosition
+		want        map[string][]int
+	}{
+		{
+			name:        "bottom",
+			issueNumber: 1,
+			pipelineID:  "done",
+			position:    "bottom",
+			want:        map[string][]int{"new": {2, 3}, "done": {4, 1}},
+		},
+		{
+			name:        "top",
+			issueNumber: 3,
+			pipelineID:  "done",
+			position:    "top",
+			want:        map[string][]int{"new": {1, 2}, "done": {3, 4}},
+		},
+		{
+			name:        "index",
+			issueNumber: 4,
+			pipelineID:  "new",
+			position:    "1",
+			want:        map[string][]int{"new": {1, 4, 2, 3}, "done": {}},
+		},
+		{
+			name:        "index past the end",
+			issueNumber: 4,
+			pipelineID:  "new",
+			position:    "10",
+			want:        map[string][]int{"new": {1, 2, 3, 4}, "done": {}},
+		},
+		{
+			name:        "within the pipeline",
+			issueNumber: 3,
+			pipelineID:  "new",
+			position:    "top",
+			want:        map[string][]int{"new": {3, 1, 2}, "done": {4}},
+		},
+		{
+			name:        "issue not on the board",
+			issueNumber: 5,
+			pipelineID:  "new",
+			position:    "bottom",
+			want:        map[string][]int{"new": {1, 2, 3, 5}, "done": {4}},
+		},
+		{
+			name:        "unknown pipeline",
+			issueNumber: 1,
+			pipelineID:  "missing",
+			position:    "bottom",
+			want:        map[string][]int{"new": {1, 2, 3}, "done": {4}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := NewBoardIndex(testBoard())
+			index.MoveIssue(test.issueNumber, test.pipelineID, test.position)
+			if got := issueNumbers(index.Board); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+			checkIndex(t, index)
+		})
+	}
+}
+
+func TestBoardIndexAddPipeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		position MovePosition
+		want     []string
+	}{
+		{name: "bottom", position: "bottom", want: []string{"new", "done", "added"}},
+		{name: "top", position: "top", want: []string{"added", "new", "done"}},
+		{name: "index", position: "1", want: []string{"new", "added", "done"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := NewBoardIndex(testBoard())
+			index.AddPipeline(Pipeline{ID: "added", Name: "Added", Issues: []BoardIssue{{IssueNumber: 9}}}, test.position)
+
+			var got []string
+			for _, pipeline := range index.Board.Pipelines {
+				got = append(got, pipeline.ID)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected pipelines %v, got %v", test.want, got)
+			}
+			checkIndex(t, index)
+
+			// Issues can be moved into and out of the added pipeline.
+			index.MoveIssue(1, "added", "top")
+			index.MoveIssue(9, "done", "bottom")
+			checkIndex(t, index)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package zenhub is a client of the ZenHub API.
+//
+// It is what the zh command line tool is built on, and can be imported by
+// other Go programs that need to talk to ZenHub:
+//
+//	client := zenhub.NewClient(zenhub.DefaultBaseURL, os.Getenv(zenhub.TokenEnvVar))
+//	board, err := client.GetBoard(workspaceID, repositoryID, false)
+package zenhub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// DefaultBaseURL is the base URL of the ZenHub API.
+	DefaultBaseURL string = "https://api.zenhub.com"
+
+	// DefaultAuthenticationHeader is the header the token is put in unless
+	// another is given with `WithAuthenticationHeader`.
+	DefaultAuthenticationHeader string = "X-Authentication-Token"
+
+	// DefaultMaxIdleConns is the default maximum number of idle (keep-alive)
+	// connections kept open by the HTTP transport.
+	//
+	// All requests go to the same host so this only needs to cover
+	// `DefaultMaxConnsPerHost`, with a little headroom for the GraphQL API.
+	DefaultMaxIdleConns uint = 8
+
+	// DefaultMaxConnsPerHost is the default maximum number of connections
+	// to a single host.
+	//
+	// The ZenHub API is rate limited to 100 requests per minute per token,
+	// so opening many connections in parallel only gets us rate limited
+	// sooner.
+	DefaultMaxConnsPerHost uint = 4
+)
+
+// TokenEnvVar is the environment variable the ZenHub token is
+// conventionally read from. Errors about the token refer to it.
+const TokenEnvVar = "ZENHUB_TOKEN"
+
+// Client is a client of the ZenHub API.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+
+	// authentication adds the token to each request.
+	authentication *AuthenticationTransport
+
+	// api is the API requests are sent to.
+	api API
+
+	// ctx is the context requests are made in, e.g. so they are cancelled
+	// with the command.
+	ctx context.Context
+
+	// timeout is how long each request has to complete. 0 means no
+	// timeout.
+	timeout time.Duration
+
+	// rateLimit tracks the rate limit reported by responses.
+	rateLimit *RateLimitTransport
+}
+
+// NewClient creates a client of the ZenHub API at `baseURL`, authenticating
+// with `token`. Requests are sent through a transport from
+// `NewHTTPTransport` with the default connection limits, wrapped in a
+// `TimeoutTransport`, unless another is given with `WithTransport`.
+func NewClient(baseURL, token string) *Client {
+	client := &Client{
+		baseURL: baseURL,
+		token:   token,
+		ctx:     context.Background(),
+		api:     APIREST,
+		authentication: &AuthenticationTransport{
+			header: DefaultAuthenticationHeader,
+			token:  token,
+		},
+	}
+	return client.WithTransport(NewTimeoutTransport(NewHTTPTransport(DefaultMaxIdleConns, DefaultMaxConnsPerHost)))
+}
+
+// NewHTTPTransport creates a transport for requests to the ZenHub API that
+// keeps up to `maxIdleConns` idle connections open, and opens at most
+// `maxConnsPerHost` connections, so bulk operations reuse connections rather
+// than opening one per request. 0 means no limit on connections per host.
+func NewHTTPTransport(maxIdleConns, maxConnsPerHost uint) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = int(maxIdleConns)
+	transport.MaxConnsPerHost = int(maxConnsPerHost)
+	transport.MaxIdleConnsPerHost = int(maxConnsPerHost)
+	if maxConnsPerHost == 0 {
+		// Idle connections to the API are only limited by `maxIdleConns`.
+		transport.MaxIdleConnsPerHost = int(maxIdleConns)
+	}
+	return transport
+}
+
+// WithTransport makes the client send requests through the given transport,
+// which is wrapped so the requests are still authenticated and kept within
+// the rate limit.
+//
+// The timeout from `WithTimeout` is applied by a `TimeoutTransport`, which
+// should wrap the transport that sends requests over the network, e.g. one
+// from `NewHTTPTransport`, so that waits for the rate limit or before a
+// retry don't count towards it.
+func (c *Client) WithTransport(transport http.RoundTripper) *Client {
+	c.rateLimit = &RateLimitTransport{transport: transport}
+	c.authentication.transport = c.rateLimit
+	c.httpClient = &http.Client{Transport: c.authentication}
+	return c
+}
+
+// WithAuthenticationHeader makes the client put the token in the given
+// header rather than `DefaultAuthenticationHeader`, e.g. for a proxy in
+// front of ZenHub Enterprise.
+func (c *Client) WithAuthenticationHeader(header string) *Client {
+	c.authentication.header = header
+	return c
+}
+
+// WithContext makes the client send requests in the given context, so
+// cancelling it cancels them.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c.ctx = ctx
+	return c
+}
+
+// WithTimeout gives each attempt at a request the client sends the given
+// time to complete. 0 means no timeout.
+func (c *Client) WithTimeout(timeout time.Duration) *Client {
+	c.timeout = timeout
+	return c
+}
+
+// WithAPI makes the client send requests to the given API. The REST API is
+// used by default.
+func (c *Client) WithAPI(api API) *Client {
+	c.api = api
+	return c
+}
+
+// API returns the API the client sends requests to.
+func (c *Client) API() API {
+	return c.api
+}
+
+// BaseURL returns the base URL the client builds endpoint URLs from.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// RateLimit returns the rate limit reported by the latest response, and
+// false if no response has reported one yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	return c.rateLimit.Latest()
+}
+
+// url returns the URL of the endpoint at the given path, formatted with
+// `args`.
+func (c *Client) url(format string, args ...interface{}) string {
+	return c.baseURL + fmt.Sprintf(format, args...)
+}
+
+// newRequest creates a request to the given URL in the client's context,
+// encoding `body` as JSON if it isn't nil. The client's timeout is carried
+// by the context for `TimeoutTransport` to apply.
+func (c *Client) newRequest(method, url string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	fields := logrus.Fields{"method": method, "url": url}
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert request %v to JSON: %w", body, err)
+		}
+		reader = bytes.NewReader(encoded)
+		fields["body"] = string(encoded)
+	}
+
+	ctx := WithAttemptTimeout(c.ctx, c.timeout)
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	logrus.WithFields(fields).Debug("Sending request")
+	return req, nil
+}
+
+// sendRequest sends a request created by `newRequest`. Responses with an
+// unsuccessful status code are turned into errors.
+//
+// The caller is responsible for closing the body of the returned response.
+func (c *Client) sendRequest(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && c.ctx.Err() == nil {
+			return nil, fmt.Errorf("request timed out after %s", c.timeout)
+		}
+		return nil, err
+	}
+
+	if err := ErrorFromResponse(resp); err != nil {
+		// Only the start of an error body is read for its message, so the
+		// rest is discarded to let the connection be reused.
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// send sends a request to the given URL, encoding `body` as JSON if it isn't
+// nil. Responses with an unsuccessful status code are turned into errors.
+//
+// The caller is responsible for closing the body of the returned response.
+func (c *Client) send(method, url string, body interface{}) (*http.Response, error) {
+	req, err := c.newRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.sendRequest(req)
+}
+
+// do sends a request like `send` and decodes the JSON response into `result`
+// if it isn't nil.
+func (c *Client) do(method, url string, body, result interface{}) error {
+	resp, err := c.send(method, url, body)
+	if err != nil {
+		return err
+	}
+	// The body is read to the end, even when it isn't decoded, so the
+	// connection can be reused.
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticationTransport is a custom transport that adds the ZenHub token to
+// the `header`.
+type AuthenticationTransport struct {
+	transport http.RoundTripper
+	header    string
+	token     string
+}
+
+// RoundTrip adds the token to the request and calls the wrapped `transport`.
+func (t *AuthenticationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Add(t.header, t.token)
+	return t.transport.RoundTrip(req)
+}
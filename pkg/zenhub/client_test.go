@@ -0,0 +1,137 @@
+package zenhub
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// emptyObject answers a request with an empty JSON object.
+func emptyObject(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(`{}`))
+}
+
+// countingServer answers every request with `handler`, counting the
+// connections opened to it.
+func countingServer(tb testing.TB, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	tb.Helper()
+	var connections int32
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	tb.Cleanup(server.Close)
+	return server, &connections
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	server, connections := countingServer(t, emptyObject)
+	client := NewClient(server.URL, "token")
+
+	request := MoveIssueRequest{PipelineID: "p1", Position: "bottom"}
+	for i := 1; i <= 10; i++ {
+		if err := client.MoveIssue("ws1", 1, i, request); err != nil {
+			t.Fatalf("failed to move issue %d: %v", i, err)
+		}
+	}
+	if *connections != 1 {
+		t.Errorf("expected sequential moves to share 1 connection, opened %d", *connections)
+	}
+}
+
+func TestClientReusesConnectionsAfterErrors(t *testing.T) {
+	// The body is longer than the part of it read for the error message,
+	// and than the transport reads by itself when a body is closed early.
+	body := `{"message": "` + strings.Repeat("x", 1<<20) + `"}`
+	server, connections := countingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(body))
+	})
+	client := NewClient(server.URL, "token")
+
+	request := MoveIssueRequest{PipelineID: "p1", Position: "bottom"}
+	for i := 1; i <= 5; i++ {
+		err := client.MoveIssue("ws1", 1, i, request)
+		if !HasStatusCode(err, http.StatusUnprocessableEntity) {
+			t.Fatalf("expected moving issue %d to fail with status code %d, got: %v", i, http.StatusUnprocessableEntity, err)
+		}
+		if !strings.HasSuffix(err.Error(), "...)") {
+			t.Errorf("expected the error message to be truncated, got: %.100s", err)
+		}
+	}
+	if *connections != 1 {
+		t.Errorf("expected failed moves to share 1 connection, opened %d", *connections)
+	}
+}
+
+func TestClientReusesConnectionsAfterUndecodedBodies(t *testing.T) {
+	// A decoder stops at the end of the first value, and moves don't decode
+	// the response at all, so neither reads the padding after it. It is
+	// longer than the transport reads by itself when a body is closed early.
+	padding := strings.Repeat(" ", 1<<20)
+	server, connections := countingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`[]` + padding))
+			return
+		}
+		w.Write([]byte(`{}` + padding))
+	})
+	client := NewClient(server.URL, "token")
+
+	request := MoveIssueRequest{PipelineID: "p1", Position: "bottom"}
+	for i := 1; i <= 5; i++ {
+		if err := client.MoveIssue("ws1", 1, i, request); err != nil {
+			t.Fatalf("failed to move issue %d: %v", i, err)
+		}
+		if _, err := client.GetWorkspaces(1); err != nil {
+			t.Fatalf("failed to get workspaces: %v", err)
+		}
+	}
+	if *connections != 1 {
+		t.Errorf("expected the requests to share 1 connection, opened %d", *connections)
+	}
+}
+
+func TestClientLimitsConnectionsPerHost(t *testing.T) {
+	server, connections := countingServer(t, emptyObject)
+	client := NewClient(server.URL, "token")
+
+	request := MoveIssueRequest{PipelineID: "p1", Position: "bottom"}
+	var wg sync.WaitGroup
+	for worker := 0; worker < 2*int(DefaultMaxConnsPerHost); worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				if err := client.MoveIssue("ws1", 1, worker*5+i+1, request); err != nil {
+					t.Errorf("failed to move issue: %v", err)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	if *connections > int32(DefaultMaxConnsPerHost) {
+		t.Errorf("expected at most %d connections, opened %d", DefaultMaxConnsPerHost, *connections)
+	}
+}
+
+func BenchmarkMoveIssue(b *testing.B) {
+	server, connections := countingServer(b, emptyObject)
+	client := NewClient(server.URL, "token")
+
+	request := MoveIssueRequest{PipelineID: "p1", Position: "bottom"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.MoveIssue("ws1", 1, i+1, request); err != nil {
+			b.Fatalf("failed to move issue: %v", err)
+		}
+	}
+	b.ReportMetric(float64(*connections), "connections")
+}
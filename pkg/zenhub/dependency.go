@@ -0,0 +1,38 @@
+package zenhub
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// DependencyIssue identifies one side of a dependency between issues.
+type DependencyIssue struct {
+	RepositoryID uint `json:"repo_id"`
+	IssueNumber  int  `json:"issue_number"`
+}
+
+// Dependency is a dependency between two issues: `Blocked` can't be worked
+// on until `Blocking` is done.
+type Dependency struct {
+	Blocking DependencyIssue `json:"blocking"`
+	Blocked  DependencyIssue `json:"blocked"`
+}
+
+// GetDependencies fetches the dependencies between issues of the given
+// repository.
+//
+// It is not supported by the GraphQL API.
+func (c *Client) GetDependencies(repositoryID uint) ([]Dependency, error) {
+	if c.api == APIGraphQL {
+		return nil, c.unsupported("get dependencies")
+	}
+
+	url := c.url("/p1/repositories/%d/dependencies", repositoryID)
+	var result struct {
+		Dependencies []Dependency `json:"dependencies"`
+	}
+	if err := c.do(http.MethodGet, url, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	return result.Dependencies, nil
+}
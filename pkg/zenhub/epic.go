@@ -0,0 +1,118 @@
+package zenhub
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// EpicIssue identifies an issue in a request to update an epic.
+type EpicIssue struct {
+	RepositoryID uint `json:"repo_id"`
+	IssueNumber  int  `json:"issue_number"`
+}
+
+// UpdateEpicIssuesRequest is the request body of a request to add issues to
+// or remove issues from an epic.
+type UpdateEpicIssuesRequest struct {
+	AddIssues    []EpicIssue `json:"add_issues,omitempty"`
+	RemoveIssues []EpicIssue `json:"remove_issues,omitempty"`
+}
+
+// UpdateEpicIssues adds issues to and removes issues from the given epic.
+//
+// It is not supported by the GraphQL API.
+func (c *Client) UpdateEpicIssues(repositoryID uint, epicID int, request UpdateEpicIssuesRequest) error {
+	if c.api == APIGraphQL {
+		return c.unsupported(fmt.Sprintf("update epic %d", epicID))
+	}
+
+	url := c.url("/p1/repositories/%d/epics/%d/update_issues", repositoryID, epicID)
+	if err := c.do(http.MethodPost, url, request, nil); err != nil {
+		return fmt.Errorf("failed to update epic %d: %w", epicID, err)
+	}
+	return nil
+}
+
+// Epic is an epic in a repository, as listed by the epics endpoint.
+type Epic struct {
+	IssueNumber  int    `json:"issue_number"`
+	RepositoryID uint   `json:"repo_id"`
+	IssueURL     string `json:"issue_url"`
+}
+
+// GetEpics fetches the epics of the given repository.
+//
+// It is not supported by the GraphQL API.
+func (c *Client) GetEpics(repositoryID uint) ([]Epic, error) {
+	if c.api == APIGraphQL {
+		return nil, c.unsupported("get epics")
+	}
+
+	url := c.url("/p1/repositories/%d/epics", repositoryID)
+	var result struct {
+		EpicIssues []Epic `json:"epic_issues"`
+	}
+	if err := c.do(http.MethodGet, url, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to get epics: %w", err)
+	}
+	return result.EpicIssues, nil
+}
+
+// EpicData is the response body of a request to get an epic's data.
+type EpicData struct {
+	// TotalEstimate is the sum of the estimates of the epic's issues.
+	TotalEstimate *Estimate       `json:"total_epic_estimates,omitempty"`
+	Estimate      *Estimate       `json:"estimate,omitempty"`
+	Pipeline      IssuePipeline   `json:"pipeline"`
+	Issues        []EpicIssueData `json:"issues"`
+}
+
+// EpicIssueData is an issue in an epic, as reported in the epic's data.
+type EpicIssueData struct {
+	IssueNumber  int           `json:"issue_number"`
+	RepositoryID uint          `json:"repo_id"`
+	Estimate     *Estimate     `json:"estimate,omitempty"`
+	IsEpic       bool          `json:"is_epic"`
+	Pipeline     IssuePipeline `json:"pipeline"`
+}
+
+// GetEpic fetches the data of the given epic, including its issues.
+//
+// It is not supported by the GraphQL API.
+func (c *Client) GetEpic(repositoryID uint, epicID int) (*EpicData, error) {
+	if c.api == APIGraphQL {
+		return nil, c.unsupported(fmt.Sprintf("get epic %d", epicID))
+	}
+
+	url := c.url("/p1/repositories/%d/epics/%d", repositoryID, epicID)
+	var epic EpicData
+	if err := c.do(http.MethodGet, url, nil, &epic); err != nil {
+		return nil, fmt.Errorf("failed to get epic %d: %w", epicID, err)
+	}
+	return &epic, nil
+}
+
+// ConvertToEpicRequest is the request body of a request to convert an issue
+// to an epic.
+type ConvertToEpicRequest struct {
+	Issues []EpicIssue `json:"issues"`
+}
+
+// ConvertToEpic converts the given issue to an epic containing `issues`.
+//
+// It is not supported by the GraphQL API.
+func (c *Client) ConvertToEpic(repositoryID uint, issueID int, issues []EpicIssue) error {
+	if c.api == APIGraphQL {
+		return c.unsupported(fmt.Sprintf("convert issue %d to an epic", issueID))
+	}
+
+	url := c.url("/p1/repositories/%d/issues/%d/convert_to_epic", repositoryID, issueID)
+	request := ConvertToEpicRequest{Issues: issues}
+	if request.Issues == nil {
+		request.Issues = []EpicIssue{}
+	}
+	if err := c.do(http.MethodPost, url, request, nil); err != nil {
+		return fmt.Errorf("failed to convert issue %d to an epic: %w", issueID, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorFromStatusCode converts the given status code into a more informative
+// error message. All 2xx status codes are successful.
+func ErrorFromStatusCode(statusCode int) error {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return nil
+	case statusCode == 400:
+		return fmt.Errorf("ZenHub rejected the request as malformed. This most likely is a bug in zh, please report it")
+	case statusCode == 401:
+		return fmt.Errorf("authentication token is not valid. Check that %s is set correctly", TokenEnvVar)
+	case statusCode == 403:
+		return fmt.Errorf("ZenHub API request limit reached. Please try again later")
+	case statusCode == 404:
+		return fmt.Errorf("endpoint not found. This most likely is a bug in zh, please report it")
+	case statusCode == 422:
+		return fmt.Errorf("ZenHub rejected the request as invalid. Check that the pipeline ID and position are valid for this workspace")
+	case statusCode == 429:
+		return fmt.Errorf("too many requests sent to ZenHub. Please wait a moment and try again")
+	case statusCode >= 500 && statusCode < 600:
+		return fmt.Errorf("ZenHub server error (status code %d). This is likely temporary, please try again later", statusCode)
+	default:
+		return fmt.Errorf("unknown status code %d. This most likely is a bug in zh, please report it", statusCode)
+	}
+}
+
+// MaxErrorBodyLength is the maximum number of bytes of a response body
+// included in an error message.
+const MaxErrorBodyLength = 512
+
+// StatusError is the error for an API response with an unsuccessful status
+// code, so callers can handle particular status codes.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// HasStatusCode reports whether the error is from an API response with the
+// given status code.
+func HasStatusCode(err error, statusCode int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == statusCode
+}
+
+// ErrorFromResponse converts the given response into a `StatusError` with a
+// more informative error message, inspecting the body where the status code
+// alone is ambiguous and including it in the error so the API's explanation
+// isn't lost. When the body is a JSON object with a `message`, only the
+// message is included.
+//
+// ZenHub uses 403 both for rate limiting and for tokens that lack permission
+// for an operation. Only the body tells them apart.
+func ErrorFromResponse(resp *http.Response) error {
+	if err := describeErrorResponse(resp); err != nil {
+		return &StatusError{StatusCode: resp.StatusCode, Err: err}
+	}
+	return nil
+}
+
+func describeErrorResponse(resp *http.Response) error {
+	statusErr := ErrorFromStatusCode(resp.StatusCode)
+	if statusErr == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"status_code": resp.StatusCode,
+			"error":       err,
+		}).Debug("Failed to read body of error response")
+		return statusErr
+	}
+
+	if resp.StatusCode == 403 && isPermissionDenied(string(body)) {
+		statusErr = fmt.Errorf("permission denied. Check that the token in %s has access to this workspace and repository", TokenEnvVar)
+	}
+
+	message := strings.TrimSpace(string(body))
+	var apiError struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiError) == nil && apiError.Message != "" {
+		message = apiError.Message
+	}
+	if message == "" {
+		return statusErr
+	}
+	if len(message) > MaxErrorBodyLength {
+		// Cut before the rune straddling the limit, so the message stays
+		// valid UTF-8.
+		n := MaxErrorBodyLength
+		for n > 0 && !utf8.RuneStart(message[n]) {
+			n--
+		}
+		message = message[:n] + "..."
+	}
+	return fmt.Errorf("%w (response: %s)", statusErr, message)
+}
+
+// isPermissionDenied reports whether the body of a 403 response says the
+// token lacks permission, rather than that it was rate limited.
+func isPermissionDenied(body string) bool {
+	message := strings.ToLower(body)
+	if strings.Contains(message, "rate limit") || strings.Contains(message, "limit exceeded") {
+		return false
+	}
+	for _, hint := range []string{"permission", "forbidden", "not authorized", "access denied"} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package zenhub
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestErrorFromStatusCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       string
+	}{
+		{statusCode: http.StatusOK},
+		{statusCode: http.StatusCreated},
+		{statusCode: http.StatusNoContent},
+		{statusCode: http.StatusBadRequest, want: "malformed"},
+		{statusCode: http.StatusUnauthorized, want: "authentication token is not valid"},
+		{statusCode: http.StatusForbidden, want: "request limit reached"},
+		{statusCode: http.StatusNotFound, want: "endpoint not found"},
+		{statusCode: http.StatusUnprocessableEntity, want: "rejected the request as invalid"},
+		{statusCode: http.StatusTooManyRequests, want: "too many requests"},
+		{statusCode: http.StatusInternalServerError, want: "server error (status code 500)"},
+		{statusCode: http.StatusServiceUnavailable, want: "server error (status code 503)"},
+		{statusCode: http.StatusConflict, want: "unknown status code 409"},
+		{statusCode: http.StatusMovedPermanently, want: "unknown status code 301"},
+	}
+
+	for _, test := range tests {
+		t.Run(http.StatusText(test.statusCode), func(t *testing.T) {
+			err := ErrorFromStatusCode(test.statusCode)
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("expected success, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got: %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestErrorFromResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       string
+	}{
+		{name: "success", statusCode: http.StatusOK, body: `{"message": "ignored"}`},
+		{name: "no body", statusCode: http.StatusNotFound, want: "endpoint not found. This most likely is a bug in zh, please report it"},
+		{name: "message", statusCode: http.StatusBadRequest, body: `{"message": "position is invalid"}`, want: "(response: position is invalid)"},
+		{name: "plain body", statusCode: http.StatusBadGateway, body: " bad gateway \n", want: "(response: bad gateway)"},
+		{name: "rate limited", statusCode: http.StatusForbidden, body: `{"message": "API rate limit exceeded"}`, want: "request limit reached"},
+		{name: "permission denied", statusCode: http.StatusForbidden, body: `{"message": "You do not have permission"}`, want: "permission denied"},
+		{name: "truncated", statusCode: http.StatusInternalServerError, body: strings.Repeat("x", MaxErrorBodyLength+1), want: strings.Repeat("x", MaxErrorBodyLength) + "...)"},
+		{name: "truncated on a rune", statusCode: http.StatusInternalServerError, body: strings.Repeat("x", MaxErrorBodyLength-1) + "é", want: "(response: " + strings.Repeat("x", MaxErrorBodyLength-1) + "...)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: test.statusCode, Body: ioutil.NopCloser(strings.NewReader(test.body))}
+			err := ErrorFromResponse(resp)
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("expected success, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("expected an error containing %q, got: %v", test.want, err)
+			}
+			if !HasStatusCode(err, test.statusCode) {
+				t.Errorf("expected the error to have status code %d, got: %v", test.statusCode, err)
+			}
+			if !utf8.ValidString(err.Error()) {
+				t.Errorf("expected the error to be valid UTF-8, got: %q", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package zenhub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// estimateURL returns the URL of the estimate endpoint of the given issue.
+func (c *Client) estimateURL(repositoryID uint, issueID int) string {
+	return c.url("/p1/repositories/%d/issues/%d/estimate", repositoryID, issueID)
+}
+
+// ClearEstimate removes the estimate from the given issue.
+func (c *Client) ClearEstimate(repositoryID uint, issueID int) error {
+	if c.api == APIGraphQL {
+		if err := c.setEstimateGraphQL(repositoryID, issueID, nil); err != nil {
+			return fmt.Errorf("failed to clear estimate: %w", err)
+		}
+		return nil
+	}
+
+	if err := c.do(http.MethodDelete, c.estimateURL(repositoryID, issueID), nil, nil); err != nil {
+		return fmt.Errorf("failed to clear estimate: %w", err)
+	}
+	return nil
+}
+
+// SetEstimateRequest is the request body of a request to set an issue's
+// estimate.
+type SetEstimateRequest struct {
+	Estimate int `json:"estimate"`
+}
+
+// SetEstimate sets the estimate of the given issue.
+func (c *Client) SetEstimate(repositoryID uint, issueID int, estimate int) error {
+	if c.api == APIGraphQL {
+		if err := c.setEstimateGraphQL(repositoryID, issueID, &estimate); err != nil {
+			return fmt.Errorf("failed to set estimate: %w", err)
+		}
+		return nil
+	}
+
+	request := SetEstimateRequest{Estimate: estimate}
+	if err := c.do(http.MethodPut, c.estimateURL(repositoryID, issueID), request, nil); err != nil {
+		return fmt.Errorf("failed to set estimate: %w", err)
+	}
+	return nil
+}
+
+// estimateValuesQuery is the GraphQL query used to get the estimate values
+// a workspace allows.
+const estimateValuesQuery = `query EstimateValues($workspaceId: ID!) {
+  workspace(id: $workspaceId) {
+    estimateSet {
+      values
+    }
+  }
+}`
+
+// GetEstimateValues fetches the estimate values the given workspace allows,
+// in ascending order. They are only available through the GraphQL API.
+func (c *Client) GetEstimateValues(workspaceID string) ([]float64, error) {
+	if err := c.requireGraphQL("get estimate values"); err != nil {
+		return nil, err
+	}
+	logrus.WithField("workspace_id", workspaceID).Debug("Sending get estimate values request")
+
+	var result struct {
+		Workspace struct {
+			EstimateSet struct {
+				Values []float64 `json:"values"`
+			} `json:"estimateSet"`
+		} `json:"workspace"`
+	}
+	variables := map[string]interface{}{
+		"workspaceId": workspaceID,
+	}
+	if err := c.GraphQL(estimateValuesQuery, variables, &result); err != nil {
+		return nil, fmt.Errorf("failed to get estimate values: %w", err)
+	}
+
+	values := result.Workspace.EstimateSet.Values
+	sort.Float64s(values)
+	return values, nil
+}
@@ -0,0 +1,73 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GraphQLPath is the path, relative to the base URL, of ZenHub's GraphQL API.
+//
+// Some operations (e.g. managing pipelines) are only available through the
+// GraphQL API.
+var GraphQLPath string = "/public/graphql"
+
+// GraphQLRequest is the request body of a GraphQL request.
+type GraphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLError is an error reported in the body of a GraphQL response.
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
+// GraphQLResponse is the response body of a GraphQL request.
+type GraphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors"`
+}
+
+// GraphQL sends the given query to the GraphQL API and decodes the data of
+// the response into `result`.
+//
+// The GraphQL API authenticates with a bearer token rather than the
+// authentication header so it is added here.
+func (c *Client) GraphQL(query string, variables map[string]interface{}, result interface{}) error {
+	url := c.baseURL + GraphQLPath
+	req, err := c.newRequest(http.MethodPost, url, GraphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("failed to create GraphQL request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return fmt.Errorf("failed to send GraphQL request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var graphQLResp GraphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&graphQLResp); err != nil {
+		return fmt.Errorf("failed to decode GraphQL response: %w", err)
+	}
+
+	if len(graphQLResp.Errors) > 0 {
+		messages := make([]string, 0, len(graphQLResp.Errors))
+		for _, e := range graphQLResp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("GraphQL request failed: %s", strings.Join(messages, "; "))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(graphQLResp.Data, result); err != nil {
+		return fmt.Errorf("failed to decode GraphQL response data: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// IssueData is the response body of a request to get an issue's ZenHub data.
+type IssueData struct {
+	Estimate *Estimate     `json:"estimate,omitempty"`
+	Pipeline IssuePipeline `json:"pipeline"`
+	IsEpic   bool          `json:"is_epic"`
+}
+
+// IssuePipeline is the pipeline an issue is in, as reported in its data.
+type IssuePipeline struct {
+	Name        string `json:"name"`
+	PipelineID  string `json:"pipeline_id"`
+	WorkspaceID string `json:"workspace_id"`
+}
+
+// GetIssueData fetches the ZenHub data of the given issue.
+func (c *Client) GetIssueData(repositoryID uint, issueID int) (*IssueData, error) {
+	if c.api == APIGraphQL {
+		return c.getIssueDataGraphQL(repositoryID, issueID)
+	}
+
+	url := c.url("/p1/repositories/%d/issues/%d", repositoryID, issueID)
+	var issue IssueData
+	if err := c.do(http.MethodGet, url, nil, &issue); err != nil {
+		return nil, fmt.Errorf("failed to get issue %d: %w", issueID, err)
+	}
+	return &issue, nil
+}
+
+// MoveIssueRequest is the request body of a request to move an issue.
+type MoveIssueRequest struct {
+	PipelineID string       `json:"pipeline_id"`
+	Position   MovePosition `json:"position"`
+}
+
+// MovePosition is where to put an issue in the pipeline it is moved to:
+// "top", "bottom" or a zero-based index.
+type MovePosition string
+
+// MarshalJSON encodes an index as a JSON number, as the move issue endpoint
+// expects, and "top" or "bottom" as a string.
+func (p MovePosition) MarshalJSON() ([]byte, error) {
+	if index, err := strconv.Atoi(string(p)); err == nil {
+		return json.Marshal(index)
+	}
+	return json.Marshal(string(p))
+}
+
+// ValidatePosition checks the given position is one accepted by the move
+// issue endpoint: "top", "bottom" or a non-negative integer index.
+func ValidatePosition(position string) error {
+	if position == "top" || position == "bottom" {
+		return nil
+	}
+	if index, err := strconv.Atoi(position); err == nil && index >= 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid position value of %s, expected top, bottom or an index of 0 or more", position)
+}
+
+// IsRelativePosition reports whether the given position counts back from the
+// end of the pipeline, e.g. -1 for the last issue.
+func IsRelativePosition(position string) bool {
+	index, err := strconv.Atoi(position)
+	return err == nil && index < 0
+}
+
+// ResolveRelativePosition converts a position counting back from the end of
+// a pipeline of `length` issues, not counting the issue being moved, into the
+// index the move issue endpoint expects. -1 puts the issue last, -2 second to
+// last and so on, down to -(length+1), which puts it first.
+func ResolveRelativePosition(position string, length int) (MovePosition, error) {
+	index, err := strconv.Atoi(position)
+	if err != nil || index >= 0 {
+		return "", fmt.Errorf("invalid relative position value of %s, expected an index below 0", position)
+	}
+	if -index > length+1 {
+		return "", fmt.Errorf("invalid position value of %s, the pipeline only has room for positions down to %d", position, -(length + 1))
+	}
+	return MovePosition(strconv.Itoa(length + 1 + index)), nil
+}
+
+// MoveIssueURL returns the URL of the endpoint to move the given issue.
+func (c *Client) MoveIssueURL(workspaceID string, repositoryID uint, issueID int) string {
+	return c.url("/p2/workspaces/%s/repositories/%d/issues/%d/moves", workspaceID, repositoryID, issueID)
+}
+
+// MoveIssue moves the given issue to the pipeline and position in the
+// request.
+func (c *Client) MoveIssue(workspaceID string, repositoryID uint, issueID int, request MoveIssueRequest) error {
+	if c.api == APIGraphQL {
+		return c.moveIssueGraphQL(repositoryID, issueID, request)
+	}
+
+	if err := c.do(http.MethodPost, c.MoveIssueURL(workspaceID, repositoryID, issueID), request, nil); err != nil {
+		return fmt.Errorf("failed to move issue between pipelines: %w", err)
+	}
+	return nil
+}
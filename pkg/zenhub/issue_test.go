@@ -0,0 +1,104 @@
+package zenhub
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoveIssueRequestJSON(t *testing.T) {
+	tests := []struct {
+		position MovePosition
+		want     string
+	}{
+		{position: "top", want: `{"pipeline_id":"p1","position":"top"}`},
+		{position: "bottom", want: `{"pipeline_id":"p1","position":"bottom"}`},
+		{position: "0", want: `{"pipeline_id":"p1","position":0}`},
+		{position: "2", want: `{"pipeline_id":"p1","position":2}`},
+	}
+
+	for _, test := range tests {
+		body, err := json.Marshal(MoveIssueRequest{PipelineID: "p1", Position: test.position})
+		if err != nil {
+			t.Errorf("failed to marshal position %q: %v", test.position, err)
+			continue
+		}
+		if string(body) != test.want {
+			t.Errorf("position %q: expected %s, got %s", test.position, test.want, body)
+		}
+	}
+}
+
+func TestValidatePosition(t *testing.T) {
+	tests := []struct {
+		position string
+		wantErr  bool
+	}{
+		{position: "top"},
+		{position: "bottom"},
+		{position: "0"},
+		{position: "3"},
+		{position: "-1", wantErr: true},
+		{position: "middle", wantErr: true},
+		{position: "1.5", wantErr: true},
+		{position: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		if err := ValidatePosition(test.position); (err != nil) != test.wantErr {
+			t.Errorf("ValidatePosition(%q): expected error %t, got: %v", test.position, test.wantErr, err)
+		}
+	}
+}
+
+func TestResolveRelativePosition(t *testing.T) {
+	tests := []struct {
+		position string
+		length   int
+		want     MovePosition
+		wantErr  bool
+	}{
+		{position: "-1", length: 0, want: "0"},
+		{position: "-1", length: 3, want: "3"},
+		{position: "-2", length: 3, want: "2"},
+		{position: "-4", length: 3, want: "0"},
+		{position: "-5", length: 3, wantErr: true},
+		{position: "-2", length: 0, wantErr: true},
+		{position: "0", length: 3, wantErr: true},
+		{position: "bottom", length: 3, wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ResolveRelativePosition(test.position, test.length)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ResolveRelativePosition(%q, %d): expected error %t, got: %v", test.position, test.length, test.wantErr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ResolveRelativePosition(%q, %d): expected %q, got %q", test.position, test.length, test.want, got)
+		}
+	}
+}
+
+func TestIsRelativePosition(t *testing.T) {
+	for position, want := range map[string]bool{
+		"-1":     true,
+		"-10":    true,
+		"0":      false,
+		"3":      false,
+		"top":    false,
+		"bottom": false,
+		"-x":     false,
+	} {
+		if got := IsRelativePosition(position); got != want {
+			t.Errorf("IsRelativePosition(%q): expected %t, got %t", position, want, got)
+		}
+	}
+}
+
+func TestMoveIssueURL(t *testing.T) {
+	client := NewClient("https://api.zenhub.com", "token")
+	want := "https://api.zenhub.com/p2/workspaces/ws1/repositories/7/issues/42/moves"
+	if got := client.MoveIssueURL("ws1", 7, 42); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,107 @@
+package zenhub
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// movePipelineMutation is the GraphQL mutation used to change the position of
+// a pipeline on the board.
+const movePipelineMutation = `mutation MovePipeline($pipelineId: ID!, $position: Int!) {
+  updatePipeline(input: {pipelineId: $pipelineId, position: $position}) {
+    pipeline {
+      id
+    }
+  }
+}`
+
+// MovePipeline moves the pipeline with the given ID to the given
+// (zero-based) index on the board.
+//
+// It is only supported by the GraphQL API.
+func (c *Client) MovePipeline(pipelineID string, position int) error {
+	if err := c.requireGraphQL("move pipeline"); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"pipeline_id": pipelineID,
+		"position":    position,
+	}).Debug("Sending move pipeline request")
+
+	variables := map[string]interface{}{
+		"pipelineId": pipelineID,
+		"position":   position,
+	}
+	if err := c.GraphQL(movePipelineMutation, variables, nil); err != nil {
+		return fmt.Errorf("failed to move pipeline: %w", err)
+	}
+	return nil
+}
+
+// createPipelineMutation is the GraphQL mutation used to create a pipeline.
+const createPipelineMutation = `mutation CreatePipeline($workspaceId: ID!, $name: String!) {
+  createPipeline(input: {workspaceId: $workspaceId, name: $name}) {
+    pipeline {
+      id
+      name
+    }
+  }
+}`
+
+// CreatePipeline creates a pipeline with the given name in the workspace.
+//
+// It is only supported by the GraphQL API.
+func (c *Client) CreatePipeline(workspaceID, name string) (*Pipeline, error) {
+	if err := c.requireGraphQL("create pipeline"); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"workspace_id": workspaceID,
+		"name":         name,
+	}).Debug("Sending create pipeline request")
+
+	var result struct {
+		CreatePipeline struct {
+			Pipeline Pipeline `json:"pipeline"`
+		} `json:"createPipeline"`
+	}
+	variables := map[string]interface{}{
+		"workspaceId": workspaceID,
+		"name":        name,
+	}
+	if err := c.GraphQL(createPipelineMutation, variables, &result); err != nil {
+		return nil, fmt.Errorf("failed to create pipeline %s: %w", name, err)
+	}
+
+	if result.CreatePipeline.Pipeline.ID == "" {
+		return nil, fmt.Errorf("failed to create pipeline %s: no pipeline returned", name)
+	}
+
+	return &result.CreatePipeline.Pipeline, nil
+}
+
+// deletePipelineMutation is the GraphQL mutation used to delete a pipeline.
+const deletePipelineMutation = `mutation DeletePipeline($pipelineId: ID!) {
+  deletePipeline(input: {pipelineId: $pipelineId}) {
+    clientMutationId
+  }
+}`
+
+// DeletePipeline deletes the pipeline with the given ID from the board.
+//
+// It is only supported by the GraphQL API.
+func (c *Client) DeletePipeline(pipelineID string) error {
+	if err := c.requireGraphQL("delete pipeline"); err != nil {
+		return err
+	}
+	logrus.WithField("pipeline_id", pipelineID).Debug("Sending delete pipeline request")
+
+	variables := map[string]interface{}{
+		"pipelineId": pipelineID,
+	}
+	if err := c.GraphQL(deletePipelineMutation, variables, nil); err != nil {
+		return fmt.Errorf("failed to delete pipeline: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package zenhub
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// RateLimitThreshold is the number of requests left in the rate limit
+	// window at or below which requests wait for the window to reset.
+	RateLimitThreshold = 2
+
+	// MaxRateLimitWait is the longest a request waits for the rate limit
+	// window to reset, in case the reset time is wrong, e.g. because of
+	// clock skew.
+	MaxRateLimitWait = time.Minute
+)
+
+// RateLimit is the state of the rate limit as reported by the
+// `X-RateLimit-*` headers of a response.
+type RateLimit struct {
+	Limit     int
+	Used      int
+	Remaining int
+	Reset     time.Time
+}
+
+func (r RateLimit) String() string {
+	return fmt.Sprintf("%d/%d used", r.Used, r.Limit)
+}
+
+// RateLimitTransport is a custom transport that keeps track of the rate
+// limit reported by the responses of the wrapped `transport`. When the
+// requests left in the current window drop to `RateLimitThreshold`, further
+// requests wait for the window to reset rather than being rate limited
+// part way through a bulk operation.
+type RateLimitTransport struct {
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	latest   RateLimit
+	observed bool
+}
+
+// RoundTrip waits for the rate limit window to reset if it is nearly used
+// up, then calls the wrapped `transport` and records the rate limit from the
+// response.
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if delay := t.delay(); delay > 0 {
+		rateLimit, _ := t.Latest()
+		logrus.WithFields(logrus.Fields{
+			"url":        req.URL.String(),
+			"rate_limit": rateLimit.String(),
+			"delay":      delay,
+		}).Warn("Rate limit nearly reached, waiting for it to reset")
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if rateLimit, ok := parseRateLimit(resp.Header); ok {
+		t.mu.Lock()
+		t.latest, t.observed = rateLimit, true
+		t.mu.Unlock()
+		logrus.WithFields(logrus.Fields{
+			"rate_limit": rateLimit.String(),
+			"remaining":  rateLimit.Remaining,
+			"reset":      rateLimit.Reset,
+		}).Debug("Rate limit")
+	}
+
+	return resp, nil
+}
+
+// Latest returns the most recently observed rate limit, and false if no
+// response has reported one yet.
+func (t *RateLimitTransport) Latest() (RateLimit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latest, t.observed
+}
+
+// delay returns how long to wait before sending the next request.
+func (t *RateLimitTransport) delay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.observed || t.latest.Remaining > RateLimitThreshold {
+		return 0
+	}
+	delay := time.Until(t.latest.Reset)
+	if delay > MaxRateLimitWait {
+		delay = MaxRateLimitWait
+	}
+	return delay
+}
+
+// parseRateLimit parses the `X-RateLimit-*` headers. `X-RateLimit-Reset` is
+// the time the window resets as a Unix timestamp. `X-RateLimit-Remaining` is
+// derived from the limit and the requests used if it isn't sent.
+func parseRateLimit(header http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	rateLimit := RateLimit{Limit: limit, Remaining: -1}
+	if used, err := strconv.Atoi(header.Get("X-RateLimit-Used")); err == nil {
+		rateLimit.Used = used
+	}
+	if remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil {
+		rateLimit.Remaining = remaining
+		if header.Get("X-RateLimit-Used") == "" {
+			rateLimit.Used = limit - remaining
+		}
+	}
+	if rateLimit.Remaining < 0 {
+		rateLimit.Remaining = limit - rateLimit.Used
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rateLimit.Reset = time.Unix(reset, 0)
+	}
+
+	return rateLimit, true
+}
@@ -0,0 +1,182 @@
+package zenhub
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// DefaultMaxRetries is the default number of times a rate limited or
+	// failed request is retried before giving up.
+	DefaultMaxRetries uint = 3
+
+	// DefaultRetryBaseDelay is the default delay before the first retry. It
+	// doubles with each retry after that.
+	DefaultRetryBaseDelay = time.Second
+)
+
+// RetryTransport is a custom transport that retries requests the wrapped
+// `transport` answers with a rate limit (403 or 429) or server error (5xx),
+// backing off exponentially with jitter between attempts.
+//
+// A `Retry-After` header on the response is honoured in place of the
+// computed delay. 403 responses caused by missing permissions are not
+// retried, as waiting won't fix them.
+//
+// Rate limits apply to the token rather than a single request, so when one
+// request is rate limited every request through the transport waits out
+// the delay, rather than concurrent requests each getting rate limited in
+// turn.
+type RetryTransport struct {
+	transport  http.RoundTripper
+	maxRetries uint
+	baseDelay  time.Duration
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// NewRetryTransport creates a transport that retries requests the given
+// transport answers with a rate limit or server error up to `maxRetries`
+// times, starting with a delay of `baseDelay`.
+func NewRetryTransport(transport http.RoundTripper, maxRetries uint, baseDelay time.Duration) *RetryTransport {
+	return &RetryTransport{
+		transport:  transport,
+		maxRetries: maxRetries,
+		baseDelay:  baseDelay,
+	}
+}
+
+// RoundTrip sends the request, retrying it while the response is retryable
+// and there are retries left.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := uint(0); ; attempt++ {
+		if err := t.waitForPause(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.transport.RoundTrip(req)
+		if err != nil || attempt >= t.maxRetries || !isRetryable(resp) {
+			return resp, err
+		}
+
+		// Without a way to rewind the body the request can't be resent.
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		delay := retryDelay(resp, t.baseDelay, attempt)
+		logrus.WithFields(logrus.Fields{
+			"url":         req.URL.String(),
+			"status_code": resp.StatusCode,
+			"attempt":     attempt + 1,
+			"delay":       delay,
+		}).Warn("Retrying request")
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+			t.pause(delay)
+		}
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// pause makes requests through the transport wait for the given delay
+// before being sent.
+func (t *RetryTransport) pause(delay time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+// waitForPause waits until requests are no longer paused by `pause`.
+func (t *RetryTransport) waitForPause(req *http.Request) error {
+	t.mu.Lock()
+	delay := time.Until(t.pausedUntil)
+	t.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	return sleep(req, delay)
+}
+
+// sleep waits for the given delay, returning early with an error if the
+// request is cancelled.
+func sleep(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isRetryable reports whether the request that got the given response is
+// worth retrying.
+//
+// The body of a 403 response is read to tell rate limiting apart from
+// missing permissions, and replaced so callers can still read it.
+func isRetryable(resp *http.Response) bool {
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode != 403 {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return true
+	}
+	return !isPermissionDenied(string(body))
+}
+
+// retryDelay returns how long to wait before retrying after the given
+// response. The `Retry-After` header, in seconds or as an HTTP date, takes
+// precedence over the jittered exponential backoff from `baseDelay`.
+func retryDelay(resp *http.Response, baseDelay time.Duration, attempt uint) time.Duration {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			if delay := time.Until(date); delay > 0 {
+				return delay
+			}
+			return 0
+		}
+	}
+
+	// Wait somewhere between half and all of the backoff so concurrent
+	// clients don't retry in lockstep.
+	backoff := baseDelay << attempt
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
@@ -0,0 +1,154 @@
+package zenhub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer answers the first `failures` requests with the given status
+// code, header and body, and the ones after that with an empty list of
+// dependencies.
+func flakyServer(t *testing.T, failures int32, statusCode int, header http.Header, body string) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statusCode)
+			w.Write([]byte(body))
+			return
+		}
+		w.Write([]byte(`{"dependencies": []}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCode   int
+		header       http.Header
+		body         string
+		wantRequests int32
+		wantErr      bool
+		wantPause    bool
+	}{
+		{
+			name:         "rate limited 403 is retried",
+			statusCode:   http.StatusForbidden,
+			body:         `{"message": "API rate limit exceeded"}`,
+			wantRequests: 2,
+			wantPause:    true,
+		},
+		{
+			name:         "429 is retried",
+			statusCode:   http.StatusTooManyRequests,
+			wantRequests: 2,
+			wantPause:    true,
+		},
+		{
+			name:         "server error is retried",
+			statusCode:   http.StatusBadGateway,
+			wantRequests: 2,
+		},
+		{
+			name:         "permission denied 403 is not retried",
+			statusCode:   http.StatusForbidden,
+			body:         `{"message": "You do not have permission to access this workspace"}`,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "not found is not retried",
+			statusCode:   http.StatusNotFound,
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := flakyServer(t, 1, test.statusCode, test.header, test.body)
+			transport := NewRetryTransport(http.DefaultTransport, 1, time.Millisecond)
+			client := NewClient(server.URL, "token").WithTransport(transport)
+
+			_, err := client.GetDependencies(1)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got: %v", test.wantErr, err)
+			}
+			if *requests != test.wantRequests {
+				t.Errorf("expected %d requests, got %d", test.wantRequests, *requests)
+			}
+			if paused := !transport.pausedUntil.IsZero(); paused != test.wantPause {
+				t.Errorf("expected pause %t, got %t", test.wantPause, paused)
+			}
+		})
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	server, requests := flakyServer(t, 10, http.StatusServiceUnavailable, nil, "")
+	transport := NewRetryTransport(http.DefaultTransport, 2, time.Millisecond)
+	client := NewClient(server.URL, "token").WithTransport(transport)
+
+	_, err := client.GetDependencies(1)
+	if !HasStatusCode(err, http.StatusServiceUnavailable) {
+		t.Fatalf("expected a 503 error, got: %v", err)
+	}
+	if *requests != 3 {
+		t.Errorf("expected 3 requests, got %d", *requests)
+	}
+}
+
+func TestRetryTransportHonoursRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"1"}}
+	server, requests := flakyServer(t, 1, http.StatusTooManyRequests, header, "")
+	transport := NewRetryTransport(http.DefaultTransport, 1, time.Millisecond)
+	client := NewClient(server.URL, "token").WithTransport(transport)
+
+	start := time.Now()
+	if _, err := client.GetDependencies(1); err != nil {
+		t.Fatalf("expected the retry to succeed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait out Retry-After of 1s, waited %s", elapsed)
+	}
+	if *requests != 2 {
+		t.Errorf("expected 2 requests, got %d", *requests)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name       string
+		retryAfter string
+		attempt    uint
+		min, max   time.Duration
+	}{
+		{name: "seconds", retryAfter: "7", min: 7 * time.Second, max: 7 * time.Second},
+		{name: "date", retryAfter: date, min: 59 * time.Minute, max: time.Hour},
+		{name: "backoff", attempt: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "doubled backoff", attempt: 2, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "invalid header", retryAfter: "soon", attempt: 1, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if test.retryAfter != "" {
+				resp.Header.Set("Retry-After", test.retryAfter)
+			}
+			delay := retryDelay(resp, 100*time.Millisecond, test.attempt)
+			if delay < test.min || delay > test.max {
+				t.Errorf("expected a delay between %s and %s, got %s", test.min, test.max, delay)
+			}
+		})
+	}
+}
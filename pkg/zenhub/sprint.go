@@ -0,0 +1,193 @@
+package zenhub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sprintsQuery is the GraphQL query used to get a page of a workspace's
+// sprints and their issues. Sprints with more issues than fit on the first
+// page are paged through with `sprintIssuesQuery`.
+const sprintsQuery = `query Sprints($workspaceId: ID!, $first: Int!, $after: String) {
+  workspace(id: $workspaceId) {
+    sprints(first: $first, after: $after) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+      nodes {
+        id
+        name
+        startAt
+        endAt
+        issues(first: $first) {
+          pageInfo {
+            hasNextPage
+            endCursor
+          }
+          nodes {
+            number
+            title
+            estimate {
+              value
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+// sprintIssuesQuery is the GraphQL query used to get the pages of a sprint's
+// issues after the first.
+const sprintIssuesQuery = `query SprintIssues($sprintId: ID!, $first: Int!, $after: String) {
+  node(id: $sprintId) {
+    ... on Sprint {
+      issues(first: $first, after: $after) {
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+        nodes {
+          number
+          title
+          estimate {
+            value
+          }
+        }
+      }
+    }
+  }
+}`
+
+// Sprint is a workspace sprint.
+type Sprint struct {
+	ID      string        `json:"id"`
+	Name    string        `json:"name"`
+	StartAt time.Time     `json:"start_at"`
+	EndAt   time.Time     `json:"end_at"`
+	Issues  []SprintIssue `json:"issues"`
+}
+
+// SprintIssue is an issue in a sprint.
+type SprintIssue struct {
+	Number   int             `json:"number"`
+	Title    string          `json:"title"`
+	Estimate *SprintEstimate `json:"estimate,omitempty"`
+}
+
+// SprintEstimate is the estimate of an issue in a sprint. Unlike the REST
+// API's estimates, the GraphQL API's can be fractional.
+type SprintEstimate struct {
+	Value float64 `json:"value"`
+}
+
+// sprintIssues is a page of a sprint's issues as returned by `sprintsQuery`
+// and `sprintIssuesQuery`.
+type sprintIssues struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []SprintIssue   `json:"nodes"`
+}
+
+// sprintsResponse is the data of the response to `sprintsQuery`.
+type sprintsResponse struct {
+	Workspace struct {
+		Sprints struct {
+			PageInfo graphQLPageInfo `json:"pageInfo"`
+			Nodes    []struct {
+				ID      string       `json:"id"`
+				Name    string       `json:"name"`
+				StartAt time.Time    `json:"startAt"`
+				EndAt   time.Time    `json:"endAt"`
+				Issues  sprintIssues `json:"issues"`
+			} `json:"nodes"`
+		} `json:"sprints"`
+	} `json:"workspace"`
+}
+
+// GetSprints fetches the sprints of the given workspace, with their issues,
+// paging through all of both.
+//
+// It is only supported by the GraphQL API.
+func (c *Client) GetSprints(workspaceID string) ([]Sprint, error) {
+	if err := c.requireGraphQL("get sprints"); err != nil {
+		return nil, err
+	}
+	logrus.WithField("workspace_id", workspaceID).Debug("Sending list sprints request")
+
+	sprints := []Sprint{}
+	after := ""
+	for {
+		var result sprintsResponse
+		variables := map[string]interface{}{
+			"workspaceId": workspaceID,
+			"first":       graphQLPageSize,
+		}
+		if after != "" {
+			variables["after"] = after
+		}
+		if err := c.GraphQL(sprintsQuery, variables, &result); err != nil {
+			return nil, fmt.Errorf("failed to list sprints: %w", err)
+		}
+
+		for _, node := range result.Workspace.Sprints.Nodes {
+			sprint := Sprint{
+				ID:      node.ID,
+				Name:    node.Name,
+				StartAt: node.StartAt,
+				EndAt:   node.EndAt,
+				Issues:  node.Issues.Nodes,
+			}
+			issues := node.Issues
+			for issues.PageInfo.HasNextPage {
+				next, err := c.getSprintIssues(node.ID, issues.PageInfo.EndCursor)
+				if err != nil {
+					return nil, err
+				}
+				issues = *next
+				sprint.Issues = append(sprint.Issues, issues.Nodes...)
+			}
+			sprints = append(sprints, sprint)
+		}
+
+		pageInfo := result.Workspace.Sprints.PageInfo
+		if !pageInfo.HasNextPage {
+			return sprints, nil
+		}
+		if pageInfo.EndCursor == "" || pageInfo.EndCursor == after {
+			return nil, fmt.Errorf("failed to list sprints: GraphQL API reported more sprints without a new cursor")
+		}
+		after = pageInfo.EndCursor
+	}
+}
+
+// getSprintIssues fetches the page of the sprint's issues after the cursor
+// `after`.
+func (c *Client) getSprintIssues(sprintID, after string) (*sprintIssues, error) {
+	if after == "" {
+		return nil, fmt.Errorf("failed to get issues of sprint %s: GraphQL API reported more issues without a cursor", sprintID)
+	}
+
+	var result struct {
+		Node *struct {
+			Issues sprintIssues `json:"issues"`
+		} `json:"node"`
+	}
+	variables := map[string]interface{}{
+		"sprintId": sprintID,
+		"first":    graphQLPageSize,
+		"after":    after,
+	}
+	if err := c.GraphQL(sprintIssuesQuery, variables, &result); err != nil {
+		return nil, fmt.Errorf("failed to get issues of sprint %s: %w", sprintID, err)
+	}
+	if result.Node == nil {
+		return nil, fmt.Errorf("failed to get issues of sprint %s: sprint not found", sprintID)
+	}
+	if result.Node.Issues.PageInfo.HasNextPage && result.Node.Issues.PageInfo.EndCursor == after {
+		return nil, fmt.Errorf("failed to get issues of sprint %s: GraphQL API reported more issues without a new cursor", sprintID)
+	}
+	return &result.Node.Issues, nil
+}
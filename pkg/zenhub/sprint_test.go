@@ -0,0 +1,53 @@
+package zenhub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetSprintsPaginates(t *testing.T) {
+	server := graphQLServer(t, func(operation string, variables map[string]interface{}) string {
+		switch {
+		case operation == "Sprints" && variables["after"] == nil:
+			return `{"data": {"workspace": {"sprints": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "s1"},
+				"nodes": [{"id": "one", "name": "Sprint 1", "startAt": "2026-01-01T00:00:00Z", "endAt": "2026-01-15T00:00:00Z", "issues": {
+					"pageInfo": {"hasNextPage": true, "endCursor": "i1"},
+					"nodes": [{"number": 1, "title": "First", "estimate": {"value": 0.5}}]
+				}}]
+			}}}}`
+		case operation == "Sprints" && variables["after"] == "s1":
+			return `{"data": {"workspace": {"sprints": {
+				"pageInfo": {"hasNextPage": false},
+				"nodes": [{"id": "two", "name": "Sprint 2", "startAt": "2026-01-15T00:00:00Z", "endAt": "2026-02-01T00:00:00Z", "issues": {
+					"pageInfo": {"hasNextPage": false},
+					"nodes": []
+				}}]
+			}}}}`
+		case operation == "SprintIssues" && variables["sprintId"] == "one" && variables["after"] == "i1":
+			return `{"data": {"node": {"issues": {
+				"pageInfo": {"hasNextPage": false},
+				"nodes": [{"number": 2, "title": "Second", "estimate": null}]
+			}}}}`
+		}
+		t.Errorf("unexpected %s query with variables %v", operation, variables)
+		return `{"errors": [{"message": "unexpected query"}]}`
+	})
+
+	client := NewClient(server.URL, "zh_token").WithAPI(APIGraphQL)
+	sprints, err := client.GetSprints("workspace")
+	if err != nil {
+		t.Fatalf("failed to get sprints: %v", err)
+	}
+	if len(sprints) != 2 || sprints[0].ID != "one" || sprints[1].ID != "two" {
+		t.Fatalf("expected sprints one and two, got %+v", sprints)
+	}
+
+	want := []SprintIssue{
+		{Number: 1, Title: "First", Estimate: &SprintEstimate{Value: 0.5}},
+		{Number: 2, Title: "Second"},
+	}
+	if !reflect.DeepEqual(sprints[0].Issues, want) {
+		t.Errorf("expected issues %+v, got %+v", want, sprints[0].Issues)
+	}
+}
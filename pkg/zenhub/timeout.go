@@ -0,0 +1,68 @@
+package zenhub
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// attemptTimeoutKey is the context key of the time each attempt at a request
+// has to complete.
+type attemptTimeoutKey struct{}
+
+// WithAttemptTimeout returns a context for requests that gives each attempt
+// at them the given time to complete once sent through a
+// `TimeoutTransport`. 0 means no timeout.
+func WithAttemptTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, attemptTimeoutKey{}, timeout)
+}
+
+// TimeoutTransport is a custom transport that gives each request the time
+// set on its context by `WithAttemptTimeout` to complete, reading the
+// response body included.
+//
+// It belongs directly around the transport that sends requests over the
+// network. Retries then each get the full timeout, and time spent waiting
+// for the rate limit to reset or before a retry doesn't count towards it.
+type TimeoutTransport struct {
+	transport http.RoundTripper
+}
+
+// NewTimeoutTransport creates a transport that applies the timeout of each
+// request to the given transport.
+func NewTimeoutTransport(transport http.RoundTripper) *TimeoutTransport {
+	return &TimeoutTransport{transport: transport}
+}
+
+// RoundTrip sends the request through the wrapped `transport` within its
+// timeout, which is released once the response body is closed.
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout, _ := req.Context().Value(attemptTimeoutKey{}).(time.Duration)
+	if timeout <= 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose is a response body that releases the request's context when
+// it is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the wrapped body and cancels the request's context.
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,48 @@
+package zenhub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeoutExcludesRetryDelay(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"dependencies": []}`))
+	}))
+	defer server.Close()
+
+	transport := NewRetryTransport(NewTimeoutTransport(http.DefaultTransport), 1, time.Millisecond)
+	client := NewClient(server.URL, "token").WithTransport(transport).WithTimeout(500 * time.Millisecond)
+	if _, err := client.GetDependencies(1); err != nil {
+		t.Fatalf("expected the retry after the 1s delay to succeed within a 500ms timeout, got: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestTimeoutAppliesToEachAttempt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token").WithTimeout(50 * time.Millisecond)
+	_, err := client.GetDependencies(1)
+	if err == nil || !strings.Contains(err.Error(), "request timed out after 50ms") {
+		t.Fatalf("expected a timeout error, got: %v", err)
+	}
+}
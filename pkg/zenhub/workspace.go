@@ -0,0 +1,28 @@
+package zenhub
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Workspace is a ZenHub workspace a repository belongs to.
+type Workspace struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Repositories []uint `json:"repositories"`
+}
+
+// GetWorkspaces fetches the workspaces the given repository belongs to.
+func (c *Client) GetWorkspaces(repositoryID uint) ([]Workspace, error) {
+	if c.api == APIGraphQL {
+		return c.getWorkspacesGraphQL(repositoryID)
+	}
+
+	url := c.url("/p2/repositories/%d/workspaces", repositoryID)
+	var workspaces []Workspace
+	if err := c.do(http.MethodGet, url, nil, &workspaces); err != nil {
+		return nil, fmt.Errorf("failed to get workspaces: %w", err)
+	}
+	return workspaces, nil
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// ProjectSyncStatusSynced is the status of an issue whose status was set
+	// in the GitHub project.
+	ProjectSyncStatusSynced string = "synced"
+
+	// ProjectSyncStatusPlanned is the status of an issue whose status would
+	// have been set in the GitHub project if it wasn't a dry run.
+	ProjectSyncStatusPlanned string = "planned"
+
+	// ProjectSyncStatusFailed is the status of an issue whose status failed
+	// to be set in the GitHub project.
+	ProjectSyncStatusFailed string = "failed"
+)
+
+// DefaultGitHubProjectStatusField is the name of the GitHub project field
+// pipelines are mirrored to unless the config file's
+// `github_project.status_field` names another.
+const DefaultGitHubProjectStatusField = "Status"
+
+// GitHubGraphQLPath is the path, relative to `GitHubBaseURL`, of GitHub's
+// GraphQL API, which is the only API for projects.
+const GitHubGraphQLPath = "/graphql"
+
+// ProjectSyncResult is the structured output of mirroring the pipeline of a
+// moved issue to a GitHub project with `sync-github-project`, reported apart
+// from the move itself.
+type ProjectSyncResult struct {
+	IssueNumber int    `json:"issue_number"`
+	Project     string `json:"project"`
+	Option      string `json:"option,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// GitHubProject is the part of a GitHub project (v2) zh uses: its status
+// field and the options of it.
+type GitHubProject struct {
+	ID            string
+	Title         string
+	StatusFieldID string
+	Options       []GitHubProjectOption
+}
+
+// GitHubProjectOption is an option of a GitHub project's single select field.
+type GitHubProjectOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Option returns the option with the given name, matched ignoring case, or
+// nil if the status field has no such option.
+func (p *GitHubProject) Option(name string) *GitHubProjectOption {
+	for i := range p.Options {
+		if strings.EqualFold(p.Options[i].Name, name) {
+			return &p.Options[i]
+		}
+	}
+	return nil
+}
+
+// GraphQL sends the given query to GitHub's GraphQL API and decodes the data
+// of the response into `result`.
+func (c *GitHubClient) GraphQL(query string, variables map[string]interface{}, result interface{}) error {
+	url := GitHubBaseURL + GitHubGraphQLPath
+	logrus.WithField("url", url).Debug("Sending GitHub GraphQL request")
+	resp, err := c.send(http.MethodPost, url, zenhub.GraphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("failed to send GraphQL request to GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := gitHubResponseError(resp, "send GraphQL request"); err != nil {
+		return err
+	}
+
+	var graphQLResp zenhub.GraphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&graphQLResp); err != nil {
+		return fmt.Errorf("failed to decode GitHub GraphQL response: %w", err)
+	}
+	if len(graphQLResp.Errors) > 0 {
+		messages := make([]string, 0, len(graphQLResp.Errors))
+		for _, e := range graphQLResp.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("GitHub GraphQL request failed: %s", strings.Join(messages, "; "))
+	}
+	if result == nil || len(graphQLResp.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(graphQLResp.Data, result); err != nil {
+		return fmt.Errorf("failed to decode GitHub GraphQL response data: %w", err)
+	}
+	return nil
+}
+
+// ParseGitHubProject parses a GitHub project given as `owner/number` or by
+// its URL, e.g. https://github.com/orgs/owner/projects/5, into its owner's
+// login and its number.
+func ParseGitHubProject(value string) (string, int, error) {
+	reference := strings.TrimSpace(value)
+	for _, prefix := range []string{"https://github.com/orgs/", "https://github.com/users/"} {
+		if strings.HasPrefix(reference, prefix) {
+			reference = strings.Replace(strings.TrimSuffix(strings.TrimPrefix(reference, prefix), "/"), "/projects/", "/", 1)
+			break
+		}
+	}
+
+	parts := strings.Split(reference, "/")
+	if len(parts) == 2 && parts[0] != "" {
+		if number, err := strconv.Atoi(parts[1]); err == nil && number > 0 {
+			return parts[0], number, nil
+		}
+	}
+	return "", 0, fmt.Errorf("invalid GitHub project %s, expected owner/number or a project URL such as https://github.com/orgs/owner/projects/5", value)
+}
+
+// projectQuery is the GraphQL query used to look up a project and its
+// status field. The owner's type, organization or user, is filled in.
+const projectQuery = `query($owner: String!, $number: Int!, $field: String!) {
+  %s(login: $owner) {
+    projectV2(number: $number) {
+      id
+      title
+      field(name: $field) {
+        ... on ProjectV2SingleSelectField {
+          id
+          options { id name }
+        }
+      }
+    }
+  }
+}`
+
+// GetProject looks up the project with the given number owned by the
+// organization or user with the given login, along with the options of its
+// single select field named `field`.
+func (c *GitHubClient) GetProject(owner string, number int, field string) (*GitHubProject, error) {
+	variables := map[string]interface{}{
+		"owner":  owner,
+		"number": number,
+		"field":  field,
+	}
+
+	// Projects are owned by organizations or users, which can't be asked
+	// for in one query as GitHub reports whichever the owner isn't as an
+	// error.
+	var err error
+	for _, ownerType := range []string{"organization", "user"} {
+		var result map[string]*struct {
+			ProjectV2 *struct {
+				ID    string `json:"id"`
+				Title string `json:"title"`
+				Field *struct {
+					ID      string                `json:"id"`
+					Options []GitHubProjectOption `json:"options"`
+				} `json:"field"`
+			} `json:"projectV2"`
+		}
+		if err = c.GraphQL(fmt.Sprintf(projectQuery, ownerType), variables, &result); err != nil {
+			continue
+		}
+		if result[ownerType] == nil || result[ownerType].ProjectV2 == nil {
+			continue
+		}
+
+		project := result[ownerType].ProjectV2
+		if project.Field == nil || project.Field.ID == "" {
+			return nil, fmt.Errorf("GitHub project %s/%d has no single select field named %s", owner, number, field)
+		}
+		return &GitHubProject{
+			ID:            project.ID,
+			Title:         project.Title,
+			StatusFieldID: project.Field.ID,
+			Options:       project.Field.Options,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub project %s/%d: %w", owner, number, err)
+	}
+	return nil, fmt.Errorf("GitHub project %s/%d not found", owner, number)
+}
+
+// addProjectItemMutation is the GraphQL mutation used to add an issue to a
+// project, which returns the issue's existing item if it is already in it.
+const addProjectItemMutation = `mutation($project: ID!, $content: ID!) {
+  addProjectV2ItemById(input: {projectId: $project, contentId: $content}) {
+    item { id }
+  }
+}`
+
+// setProjectItemOptionMutation is the GraphQL mutation used to set a single
+// select field of a project item.
+const setProjectItemOptionMutation = `mutation($project: ID!, $item: ID!, $field: ID!, $option: String!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: {singleSelectOptionId: $option}}) {
+    projectV2Item { id }
+  }
+}`
+
+// SetProjectStatus sets the status field of the issue with the given node ID
+// in the project to the given option, adding the issue to the project if it
+// isn't in it yet.
+func (c *GitHubClient) SetProjectStatus(project *GitHubProject, issueNodeID, optionID string) error {
+	var added struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		} `json:"addProjectV2ItemById"`
+	}
+	variables := map[string]interface{}{
+		"project": project.ID,
+		"content": issueNodeID,
+	}
+	if err := c.GraphQL(addProjectItemMutation, variables, &added); err != nil {
+		return fmt.Errorf("failed to add issue to GitHub project %s: %w", project.Title, err)
+	}
+
+	variables = map[string]interface{}{
+		"project": project.ID,
+		"item":    added.AddProjectV2ItemByID.Item.ID,
+		"field":   project.StatusFieldID,
+		"option":  optionID,
+	}
+	if err := c.GraphQL(setProjectItemOptionMutation, variables, nil); err != nil {
+		return fmt.Errorf("failed to set status in GitHub project %s: %w", project.Title, err)
+	}
+	return nil
+}
+
+// ProjectSyncer mirrors the pipelines issues are moved to onto the status
+// field of the GitHub project given by `sync-github-project`.
+type ProjectSyncer struct {
+	github       *GitHubClient
+	repositoryID uint
+	name         string
+	project      *GitHubProject
+	statuses     map[string]string
+	dryRun       bool
+}
+
+// NewProjectSyncerFromContext looks up the GitHub project given by the
+// `sync-github-project` flag and checks the config file's
+// `github_project.statuses` against its status options. It returns nil if
+// `sync-github-project` isn't set.
+//
+// The project is looked up before any issue is moved, so a missing project
+// doesn't leave the issues moved but out of sync.
+func NewProjectSyncerFromContext(ctx *cli.Context, repositoryID uint) (*ProjectSyncer, error) {
+	name := strings.TrimSpace(ctx.String("sync-github-project"))
+	if name == "" {
+		return nil, nil
+	}
+	owner, number, err := ParseGitHubProject(name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(os.Getenv(GitHubTokenEnvVar)) == "" {
+		return nil, fmt.Errorf("sync-github-project needs %s to be set to change projects on GitHub", GitHubTokenEnvVar)
+	}
+
+	config := fileConfig.GitHubProject
+	field := strings.TrimSpace(config.StatusField)
+	if field == "" {
+		field = DefaultGitHubProjectStatusField
+	}
+
+	github, err := NewGitHubClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	project, err := github.GetProject(owner, number, field)
+	if err != nil {
+		return nil, err
+	}
+	for pipeline, option := range config.Statuses {
+		if project.Option(option) == nil {
+			return nil, fmt.Errorf("github_project.statuses maps pipeline %s to %s, which isn't an option of field %s in GitHub project %s", pipeline, option, field, name)
+		}
+	}
+	logrus.WithFields(logrus.Fields{
+		"project":    name,
+		"project_id": project.ID,
+		"field_id":   project.StatusFieldID,
+	}).Debug("Resolved GitHub project")
+
+	return &ProjectSyncer{
+		github:       github,
+		repositoryID: repositoryID,
+		name:         name,
+		project:      project,
+		statuses:     config.Statuses,
+		dryRun:       ctx.Bool("dry-run"),
+	}, nil
+}
+
+// option returns the status option the given pipeline is mirrored to: the
+// one it is mapped to in the config file, matched by the pipeline's name
+// ignoring case or by its ID, or else the option named like the pipeline.
+func (s *ProjectSyncer) option(pipeline *zenhub.Pipeline) (*GitHubProjectOption, error) {
+	name := pipeline.Name
+	for mapped, option := range s.statuses {
+		if strings.EqualFold(mapped, pipeline.Name) || mapped == pipeline.ID {
+			name = option
+			break
+		}
+	}
+	if option := s.project.Option(name); option != nil {
+		return option, nil
+	}
+	return nil, fmt.Errorf("no status in GitHub project %s for pipeline %s, map it to one under github_project.statuses in the config file", s.name, pipeline.Name)
+}
+
+// Sync sets the status of the given issue in the project to the one its
+// pipeline is mirrored to.
+func (s *ProjectSyncer) Sync(issueNumber int, pipeline *zenhub.Pipeline) ProjectSyncResult {
+	result := ProjectSyncResult{IssueNumber: issueNumber, Project: s.name, Status: ProjectSyncStatusSynced}
+	err := func() error {
+		option, err := s.option(pipeline)
+		if err != nil {
+			return err
+		}
+		result.Option = option.Name
+
+		issue, err := s.github.GetIssue(s.repositoryID, issueNumber)
+		if err != nil {
+			return err
+		}
+		return s.github.SetProjectStatus(s.project, issue.NodeID, option.ID)
+	}()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"issue_id": issueNumber,
+			"project":  s.name,
+			"error":    err,
+		}).Error("Failed to sync issue to GitHub project")
+		result.Status = ProjectSyncStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	if s.dryRun {
+		result.Status = ProjectSyncStatusPlanned
+	}
+	return result
+}
+
+// PrintProjectSyncResult prints the result of syncing an issue to a GitHub
+// project as text. Failures are logged by `ProjectSyncer.Sync` instead.
+func PrintProjectSyncResult(result ProjectSyncResult) {
+	switch result.Status {
+	case ProjectSyncStatusSynced:
+		fmt.Printf("Successfully set status of issue %d to %s in GitHub project %s\n", result.IssueNumber, result.Option, result.Project)
+	case ProjectSyncStatusPlanned:
+		fmt.Printf("Would set status of issue %d to %s in GitHub project %s\n", result.IssueNumber, result.Option, result.Project)
+	}
+}
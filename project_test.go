@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nick96/zh/pkg/zenhub"
+)
+
+func TestParseGitHubProject(t *testing.T) {
+	tests := []struct {
+		value  string
+		owner  string
+		number int
+	}{
+		{value: "nick96/5", owner: "nick96", number: 5},
+		{value: "https://github.com/orgs/acme/projects/12", owner: "acme", number: 12},
+		{value: "https://github.com/users/nick96/projects/3/", owner: "nick96", number: 3},
+		{value: "nick96"},
+		{value: "nick96/zero"},
+		{value: "/5"},
+		{value: "nick96/0"},
+	}
+
+	for _, test := range tests {
+		owner, number, err := ParseGitHubProject(test.value)
+		if test.owner == "" {
+			if err == nil {
+				t.Errorf("ParseGitHubProject(%q): expected an error, got %s/%d", test.value, owner, number)
+			}
+			continue
+		}
+		if err != nil || owner != test.owner || number != test.number {
+			t.Errorf("ParseGitHubProject(%q): expected %s/%d, got %s/%d: %v", test.value, test.owner, test.number, owner, number, err)
+		}
+	}
+}
+
+// projectServer is a fake GitHub API with a user owned project whose Status
+// field has Todo, Doing and Done options, recording the options issues are
+// set to by their item IDs.
+func projectServer(t *testing.T) map[string]string {
+	t.Helper()
+	set := make(map[string]string)
+	withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != GitHubGraphQLPath {
+			var number int
+			fmt.Sscanf(r.URL.Path, "/repositories/1/issues/%d", &number)
+			fmt.Fprintf(w, `{"node_id": "I_%d", "state": "open"}`, number)
+			return
+		}
+
+		var request zenhub.GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode GraphQL request: %v", err)
+		}
+		switch {
+		case strings.Contains(request.Query, "organization(login: $owner)"):
+			fmt.Fprint(w, `{"data": {"organization": null}, "errors": [{"message": "Could not resolve to an Organization"}]}`)
+		case strings.Contains(request.Query, "user(login: $owner)"):
+			if request.Variables["field"] != "Status" {
+				t.Errorf("expected the Status field to be looked up, got %v", request.Variables["field"])
+			}
+			fmt.Fprint(w, `{"data": {"user": {"projectV2": {"id": "PVT_1", "title": "Roadmap", "field": {
+				"id": "F_1", "options": [{"id": "O_todo", "name": "Todo"}, {"id": "O_doing", "name": "Doing"}, {"id": "O_done", "name": "Done"}]
+			}}}}}`)
+		case strings.Contains(request.Query, "addProjectV2ItemById"):
+			fmt.Fprintf(w, `{"data": {"addProjectV2ItemById": {"item": {"id": "PVTI_%s"}}}}`, request.Variables["content"])
+		case strings.Contains(request.Query, "updateProjectV2ItemFieldValue"):
+			if request.Variables["project"] != "PVT_1" || request.Variables["field"] != "F_1" {
+				t.Errorf("expected the project's status field to be set, got %v", request.Variables)
+			}
+			set[request.Variables["item"].(string)] = request.Variables["option"].(string)
+			fmt.Fprint(w, `{"data": {"updateProjectV2ItemFieldValue": {"projectV2Item": {"id": "x"}}}}`)
+		default:
+			t.Errorf("unexpected GraphQL request: %s", request.Query)
+		}
+	})
+	setenv(t, GitHubTokenEnvVar, "github_token")
+	return set
+}
+
+func TestMoveIssueCommandSyncGitHubProject(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/board") {
+			fmt.Fprint(w, `{"pipelines": [
+				{"id": "p1", "name": "Backlog", "issues": []},
+				{"id": "p2", "name": "In Progress", "issues": []},
+				{"id": "p3", "name": "Done", "issues": []}
+			]}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	set := projectServer(t)
+
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "zh", ConfigFileName)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("failed to create config directory: %v", err)
+	}
+	config := "github_project:\n  statuses:\n    in progress: Doing\n"
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--sync-github-project", "nick96/5", "1", "2", "In Progress")
+	})
+	if err != nil {
+		t.Fatalf("failed to move issues: %v", err)
+	}
+	if want := map[string]string{"PVTI_I_1": "O_doing", "PVTI_I_2": "O_doing"}; fmt.Sprint(set) != fmt.Sprint(want) {
+		t.Errorf("expected the mapped status to be set on both issues %v, got %v", want, set)
+	}
+	if !strings.Contains(output, "Successfully set status of issue 2 to Doing in GitHub project nick96/5") {
+		t.Errorf("expected the sync to be reported, got:\n%s", output)
+	}
+
+	// Unmapped pipelines are mirrored to the status named like them.
+	output = captureStdout(t, func() {
+		err = runApp(t, server, "--output", "json", "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--sync-github-project", "nick96/5", "3", "Done")
+	})
+	if err != nil {
+		t.Fatalf("failed to move issue: %v", err)
+	}
+	if set["PVTI_I_3"] != "O_done" {
+		t.Errorf("expected issue 3 to be set to Done, got %v", set)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	var result ProjectSyncResult
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &result); err != nil || result.Status != ProjectSyncStatusSynced || result.Option != "Done" {
+		t.Errorf("expected a synced result after the move, got %s: %v", output, err)
+	}
+
+	// A pipeline without a status fails the sync, but not the move.
+	captureStdout(t, func() {
+		err = runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--sync-github-project", "nick96/5", "4", "Backlog")
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to sync 1 of 1 moved issues to GitHub project nick96/5") {
+		t.Errorf("expected the sync to fail, got: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("github_project:\n  statuses:\n    Backlog: Later\n"), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	requests := len(server.Requests())
+	err = runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--sync-github-project", "nick96/5", "4", "Backlog")
+	if err == nil || !strings.Contains(err.Error(), "maps pipeline Backlog to Later") {
+		t.Errorf("expected a status the project doesn't have to be an error, got: %v", err)
+	}
+	if len(server.Requests()) != requests {
+		t.Errorf("expected nothing to be moved with an invalid mapping")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacted replaces secrets in log output.
+const Redacted = "***"
+
+// RedactHeaders returns a copy of the headers with the values of those
+// carrying the token replaced by `Redacted`, for logging request details.
+func RedactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if strings.EqualFold(name, AuthenticationHeader) || strings.EqualFold(name, "Authorization") {
+			for i := range redacted[name] {
+				redacted[name][i] = Redacted
+			}
+		}
+	}
+	return redacted
+}
+
+// RedactionHook is a logrus hook that replaces secrets registered with
+// `AddSecret` wherever they appear in a log entry's message or fields.
+//
+// Headers are redacted before they are logged, this is the guard against a
+// secret finding its way in some other way, e.g. in an error message.
+type RedactionHook struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// redactionHook is the hook installed on the standard logger by `main`.
+var redactionHook = &RedactionHook{}
+
+// AddSecret registers a secret to be redacted from log entries.
+func (h *RedactionHook) AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, existing := range h.secrets {
+		if existing == secret {
+			return
+		}
+	}
+	h.secrets = append(h.secrets, secret)
+}
+
+// Levels returns all levels, so no entry escapes redaction.
+func (h *RedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts the secrets from the entry's message and fields.
+func (h *RedactionHook) Fire(entry *logrus.Entry) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.secrets) == 0 {
+		return nil
+	}
+
+	entry.Message = h.redact(entry.Message)
+	for key, value := range entry.Data {
+		switch value := value.(type) {
+		case string:
+			entry.Data[key] = h.redact(value)
+		case error:
+			entry.Data[key] = h.redact(value.Error())
+		case fmt.Stringer:
+			entry.Data[key] = h.redact(value.String())
+		}
+	}
+	return nil
+}
+
+// Redact replaces the registered secrets in the given string, e.g. for
+// output that doesn't go through the logger.
+func (h *RedactionHook) Redact(s string) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.redact(s)
+}
+
+func (h *RedactionHook) redact(s string) string {
+	for _, secret := range h.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
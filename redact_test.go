@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureLogs sends the standard logger's output, redacted as `main` does,
+// to the returned buffer for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	logger := logrus.StandardLogger()
+	out, level, formatter := logger.Out, logger.GetLevel(), logger.Formatter
+	hooks := logrus.LevelHooks{}
+	for level, levelHooks := range logger.Hooks {
+		hooks[level] = append(hooks[level], levelHooks...)
+	}
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.AddHook(redactionHook)
+	t.Cleanup(func() {
+		logger.SetOutput(out)
+		logger.SetLevel(level)
+		logger.SetFormatter(formatter)
+		logger.ReplaceHooks(hooks)
+	})
+	return &buf
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set(AuthenticationHeader, "secret")
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Content-Type", "application/json")
+
+	redacted := RedactHeaders(header)
+	for _, name := range []string{AuthenticationHeader, "Authorization"} {
+		if value := redacted.Get(name); value != Redacted {
+			t.Errorf("expected %s to be redacted, got %q", name, value)
+		}
+	}
+	if value := redacted.Get("Content-Type"); value != "application/json" {
+		t.Errorf("expected Content-Type to be kept, got %q", value)
+	}
+	if value := header.Get(AuthenticationHeader); value != "secret" {
+		t.Errorf("expected the original headers to be left alone, got %q", value)
+	}
+}
+
+func TestRedactionHook(t *testing.T) {
+	hook := &RedactionHook{}
+	hook.AddSecret("secret")
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(hook)
+
+	logger.WithFields(logrus.Fields{
+		"token": "secret",
+		"error": errors.New("request with secret failed"),
+	}).Error("Failed with secret")
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("expected the secret to be redacted, got: %s", buf.String())
+	}
+	if count := strings.Count(buf.String(), Redacted); count != 3 {
+		t.Errorf("expected 3 redactions, got %d in: %s", count, buf.String())
+	}
+}
+
+func TestDebugHTTPRedactsToken(t *testing.T) {
+	// The server echoing the token back makes sure it is redacted from
+	// bodies and errors too, not only from headers.
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, `{"message": "token %s is not valid"}`, r.Header.Get(AuthenticationHeader))
+	})
+	logs := captureLogs(t)
+
+	err := runApp(t, server, "--verbose", "--debug-http", "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "42", testPipelineID)
+	if err == nil {
+		t.Fatal("expected the move to fail")
+	}
+	logrus.WithField("error", err).Error("Failed to run app")
+
+	if !strings.Contains(logs.String(), "HTTP request") {
+		t.Fatalf("expected requests to be logged, got: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), testToken) {
+		t.Errorf("expected the token to be redacted from the logs, got: %s", logs.String())
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// MoveRules routes the issues moved by `issue mv --rules` to pipelines by
+// their GitHub labels and assignees. Each issue is moved to the pipeline of
+// the first rule it matches.
+type MoveRules struct {
+	Rules []MoveRule `yaml:"rules"`
+}
+
+// MoveRule moves the issues matching it to its pipeline, given by ID, name
+// or pipeline alias like the pipeline argument of `issue mv`.
+type MoveRule struct {
+	// Name is how the rule is reported on the issues it matches. It
+	// defaults to the rule's number, counting from 1, in the rules file.
+	Name     string    `yaml:"name"`
+	Match    RuleMatch `yaml:"match"`
+	Pipeline string    `yaml:"pipeline"`
+}
+
+// RuleMatch is what an issue must have on GitHub to match a rule. An issue
+// matches if it has every one that is set.
+type RuleMatch struct {
+	// Label is the name of a label the issue has, matched ignoring case.
+	Label string `yaml:"label"`
+
+	// Assignee is the login of a user the issue is assigned to, matched
+	// ignoring case, or `AssigneeMe` for the user GITHUB_TOKEN belongs to.
+	Assignee string `yaml:"assignee"`
+}
+
+// Matches reports whether the issue matches the rule.
+func (r MoveRule) Matches(issue *GitHubIssue) bool {
+	if r.Match.Label != "" && !issue.HasLabel(r.Match.Label) {
+		return false
+	}
+	if r.Match.Assignee != "" && !issue.IsAssignedTo(r.Match.Assignee) {
+		return false
+	}
+	return true
+}
+
+// Match returns the first rule the issue matches, or nil if it matches
+// none.
+func (r *MoveRules) Match(issue *GitHubIssue) *MoveRule {
+	for i := range r.Rules {
+		if r.Rules[i].Matches(issue) {
+			return &r.Rules[i]
+		}
+	}
+	return nil
+}
+
+// LoadMoveRules reads the rules file at the given path.
+func LoadMoveRules(path string) (*MoveRules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rules file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	rules, err := ParseMoveRules(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseMoveRules parses a YAML rules file, naming the rules without a name.
+// Unknown keys are errors, so typos don't go unnoticed.
+func ParseMoveRules(r io.Reader) (*MoveRules, error) {
+	var rules MoveRules
+	decoder := yaml.NewDecoder(r)
+	decoder.SetStrict(true)
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if len(rules.Rules) == 0 {
+		return nil, errors.New("no rules found, expected a list of them under rules")
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			rule.Name = strconv.Itoa(i + 1)
+		}
+		rule.Match.Label = strings.TrimSpace(rule.Match.Label)
+		rule.Match.Assignee = strings.TrimSpace(rule.Match.Assignee)
+		rule.Pipeline = strings.TrimSpace(rule.Pipeline)
+		if rule.Match.Label == "" && rule.Match.Assignee == "" {
+			return nil, fmt.Errorf("rule %s matches every issue, expected a label or assignee to match", rule.Name)
+		}
+		if rule.Pipeline == "" {
+			return nil, fmt.Errorf("rule %s has no pipeline to move issues to", rule.Name)
+		}
+	}
+	return &rules, nil
+}
+
+// Resolve resolves the rules' pipelines to pipeline IDs on the board and
+// their assignees to GitHub logins, so nothing is left to fail once issues
+// start moving.
+func (r *MoveRules) Resolve(index *zenhub.BoardIndex, github *GitHubClient) error {
+	me := ""
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+
+		pipeline := rule.Pipeline
+		if alias, ok := fileConfig.PipelineAlias(pipeline); ok {
+			pipeline = alias
+		}
+		pipelineID, err := MatchPipelineArg(index, pipeline)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		rule.Pipeline = pipelineID
+
+		if rule.Match.Assignee == AssigneeMe && me == "" {
+			if me, err = ResolveAssignee(github, AssigneeMe); err != nil {
+				return fmt.Errorf("rule %s: %w", rule.Name, err)
+			}
+		}
+		if rule.Match.Assignee == AssigneeMe {
+			rule.Match.Assignee = me
+		} else {
+			rule.Match.Assignee = strings.TrimPrefix(rule.Match.Assignee, "@")
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"rule":        rule.Name,
+			"label":       rule.Match.Label,
+			"assignee":    rule.Match.Assignee,
+			"pipeline_id": rule.Pipeline,
+		}).Debug("Resolved rule")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMoveRules(t *testing.T) {
+	rules, err := ParseMoveRules(strings.NewReader(`
+rules:
+  - name: bugs
+    match: {label: bug}
+    pipeline: Triage
+  - match: {label: ui, assignee: octocat}
+    pipeline: Design
+`))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+	if len(rules.Rules) != 2 || rules.Rules[0].Name != "bugs" || rules.Rules[1].Name != "2" {
+		t.Errorf("expected rules named bugs and 2, got %+v", rules.Rules)
+	}
+
+	issue := &GitHubIssue{
+		Labels:    []GitHubLabel{{Name: "UI"}},
+		Assignees: []GitHubUser{{Login: "octocat"}},
+	}
+	if rule := rules.Match(issue); rule == nil || rule.Name != "2" {
+		t.Errorf("expected the issue to match rule 2, got %+v", rule)
+	}
+	issue.Labels = append(issue.Labels, GitHubLabel{Name: "bug"})
+	if rule := rules.Match(issue); rule == nil || rule.Name != "bugs" {
+		t.Errorf("expected the first matching rule to win, got %+v", rule)
+	}
+	if rule := rules.Match(&GitHubIssue{Labels: []GitHubLabel{{Name: "ui"}}}); rule != nil {
+		t.Errorf("expected a rule to need every match, got %+v", rule)
+	}
+
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{name: "empty", yaml: "", want: "no rules found"},
+		{name: "no match", yaml: "rules: [{pipeline: Triage}]", want: "rule 1 matches every issue"},
+		{name: "no pipeline", yaml: "rules: [{match: {label: bug}}]", want: "rule 1 has no pipeline"},
+		{name: "unknown key", yaml: "rules: [{match: {labels: bug}, pipeline: Triage}]", want: "not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseMoveRules(strings.NewReader(test.yaml))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got: %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestMoveIssueCommandRules(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/board") {
+			fmt.Fprint(w, `{"pipelines": [
+				{"id": "p1", "name": "Triage", "issues": []},
+				{"id": "p2", "name": "Doing", "issues": []}
+			]}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	withGitHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			fmt.Fprint(w, `{"login": "nick96"}`)
+		case "/repositories/1/issues/1":
+			fmt.Fprint(w, `{"state": "open", "labels": [{"name": "bug"}]}`)
+		case "/repositories/1/issues/2":
+			fmt.Fprint(w, `{"state": "open", "labels": [{"name": "bug"}], "assignees": [{"login": "Nick96"}]}`)
+		case "/repositories/1/issues/3":
+			fmt.Fprint(w, `{"state": "open", "labels": [{"name": "docs"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	setenv(t, GitHubTokenEnvVar, "github_token")
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	contents := `
+rules:
+  - name: mine
+    match: {assignee: "@me"}
+    pipeline: doing
+  - name: bugs
+    match: {label: bug}
+    pipeline: Triage
+`
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("failed to write rules file: %v", err)
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runApp(t, server, "--output", "json", "--repository-id", "1", "--workspace-id", "ws1", "issue", "mv", "--rules", path, "1", "2", "3")
+	})
+	if err != nil {
+		t.Fatalf("failed to move issues: %v", err)
+	}
+
+	results := make(map[int]MoveResult)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var result MoveResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("expected a move result per line, got %q: %v", line, err)
+		}
+		results[result.IssueID] = result
+	}
+	if result := results[1]; result.Status != MoveStatusMoved || result.Rule != "bugs" || result.PipelineID != "p1" {
+		t.Errorf("expected issue 1 moved to p1 by rule bugs, got %+v", result)
+	}
+	if result := results[2]; result.Status != MoveStatusMoved || result.Rule != "mine" || result.PipelineID != "p2" {
+		t.Errorf("expected issue 2 moved to p2 by rule mine, got %+v", result)
+	}
+	if result := results[3]; result.Status != MoveStatusSkipped || result.Reason != "it matches no rule" {
+		t.Errorf("expected issue 3 to be skipped, got %+v", result)
+	}
+
+	var moves []string
+	for _, request := range server.Requests() {
+		if request.Method == http.MethodPost {
+			moves = append(moves, request.Path+" "+request.Body)
+		}
+	}
+	if len(moves) != 2 {
+		t.Errorf("expected 2 moves, got %v", moves)
+	}
+}
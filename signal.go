@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// ExitCodeInterrupted is the exit code when zh is interrupted by a signal,
+// following the shell convention of 128 plus SIGINT's number.
+const ExitCodeInterrupted = 130
+
+// NotifyContext returns a copy of the parent context that is cancelled when
+// one of the given signals arrives, or when the returned stop function is
+// called. Commands check the context between operations so they can stop
+// cleanly and report what they already did.
+//
+// This is `signal.NotifyContext`, which isn't available in the Go version zh
+// supports.
+func NotifyContext(parent context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		select {
+		case <-ch:
+			// A second signal kills zh as usual, in case stopping
+			// cleanly takes too long.
+			signal.Stop(ch)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel()
+	}
+}
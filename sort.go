@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nick96/zh/pkg/zenhub"
+)
+
+// ParseSort parses the `sort` flag of a list command: one of the given sort
+// keys, optionally followed by `:asc` or `:desc`. It returns the key and
+// whether to sort in descending order.
+func ParseSort(value string, keys ...string) (string, bool, error) {
+	key, order := value, "asc"
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		key, order = value[:i], value[i+1:]
+	}
+
+	valid := false
+	for _, k := range keys {
+		valid = valid || key == k
+	}
+	if !valid {
+		return "", false, fmt.Errorf("invalid sort value of %s, expected one of %s, optionally followed by :asc or :desc",
+			value, joinAlternatives(keys))
+	}
+
+	switch order {
+	case "asc":
+		return key, false, nil
+	case "desc":
+		return key, true, nil
+	default:
+		return "", false, fmt.Errorf("invalid sort order of %s in %s, expected asc or desc", order, value)
+	}
+}
+
+// joinAlternatives joins the values as a list of alternatives, e.g. "a, b or
+// c".
+func joinAlternatives(values []string) string {
+	if len(values) < 2 {
+		return strings.Join(values, "")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
+
+// sortBy stably sorts the slice by `compare` of the elements at two indexes,
+// in descending order if set. Elements comparing equal keep their order, so ties are broken
+// by the order they were listed in.
+func sortBy(slice interface{}, compare func(i, j int) int, descending bool) {
+	sort.SliceStable(slice, func(i, j int) bool {
+		if descending {
+			return compare(i, j) > 0
+		}
+		return compare(i, j) < 0
+	})
+}
+
+const (
+	// SortName sorts pipelines or workspaces by their name, ignoring case.
+	SortName string = "name"
+
+	// SortID sorts pipelines or workspaces by their ID.
+	SortID string = "id"
+
+	// SortIssueCount sorts pipelines by the number of issues in them.
+	SortIssueCount string = "issue_count"
+
+	// SortIssueNumber sorts issues by their number.
+	SortIssueNumber string = "issue_number"
+
+	// SortEstimate sorts issues by their estimate, unestimated issues first.
+	SortEstimate string = "estimate"
+)
+
+// PipelineSortKeys are the keys pipeline ls can sort by.
+var PipelineSortKeys = []string{SortName, SortID, SortIssueCount}
+
+// SortPipelines sorts the pipelines by the given key. Ties keep their board
+// order.
+func SortPipelines(pipelines []PipelineSummary, key string, descending bool) {
+	sortBy(pipelines, func(i, j int) int {
+		a, b := pipelines[i], pipelines[j]
+		switch key {
+		case SortName:
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case SortID:
+			return strings.Compare(a.ID, b.ID)
+		case SortIssueCount:
+			return compareInts(a.IssueCount, b.IssueCount)
+		}
+		return 0
+	}, descending)
+}
+
+// WorkspaceSortKeys are the keys workspace ls can sort by.
+var WorkspaceSortKeys = []string{SortName, SortID}
+
+// SortWorkspaces sorts the workspaces by the given key. Ties keep the order
+// the API listed them in.
+func SortWorkspaces(workspaces []zenhub.Workspace, key string, descending bool) {
+	sortBy(workspaces, func(i, j int) int {
+		a, b := workspaces[i], workspaces[j]
+		switch key {
+		case SortName:
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case SortID:
+			return strings.Compare(a.ID, b.ID)
+		}
+		return 0
+	}, descending)
+}
+
+// BoardIssueSortKeys are the keys board ls can sort the issues in each
+// pipeline by.
+var BoardIssueSortKeys = []string{SortIssueNumber, SortEstimate}
+
+// SortBoardIssues sorts the issues in each of the board's pipelines by the
+// given key, leaving the pipelines in board order. Ties keep their position
+// in the pipeline.
+func SortBoardIssues(board *zenhub.Board, key string, descending bool) {
+	for _, pipeline := range board.Pipelines {
+		issues := pipeline.Issues
+		sortBy(issues, func(i, j int) int {
+			a, b := issues[i], issues[j]
+			switch key {
+			case SortIssueNumber:
+				return compareInts(a.IssueNumber, b.IssueNumber)
+			case SortEstimate:
+				return compareInts(estimateValue(a.Estimate), estimateValue(b.Estimate))
+			}
+			return 0
+		}, descending)
+	}
+}
+
+// estimateValue returns the value of the estimate, or -1 for no estimate so
+// unestimated issues sort before those estimated at 0.
+func estimateValue(estimate *zenhub.Estimate) int {
+	if estimate == nil {
+		return -1
+	}
+	return estimate.Value
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func derefFloat(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nick96/zh/pkg/zenhub"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		value          string
+		wantKey        string
+		wantDescending bool
+		wantErr        string
+	}{
+		{value: "name", wantKey: SortName},
+		{value: "name:asc", wantKey: SortName},
+		{value: "issue_count:desc", wantKey: SortIssueCount, wantDescending: true},
+		{value: "estimate", wantErr: "expected one of name, id or issue_count"},
+		{value: "name:up", wantErr: "expected asc or desc"},
+		{value: "", wantErr: "invalid sort value"},
+	}
+
+	for _, test := range tests {
+		key, descending, err := ParseSort(test.value, PipelineSortKeys...)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("ParseSort(%q): expected an error containing %q, got: %v", test.value, test.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSort(%q): unexpected error: %v", test.value, err)
+			continue
+		}
+		if key != test.wantKey || descending != test.wantDescending {
+			t.Errorf("ParseSort(%q): expected %s descending %t, got %s descending %t",
+				test.value, test.wantKey, test.wantDescending, key, descending)
+		}
+	}
+}
+
+func TestSortPipelines(t *testing.T) {
+	pipelines := []PipelineSummary{
+		{ID: "c", Name: "Backlog", IssueCount: 2},
+		{ID: "a", Name: "done", IssueCount: 0},
+		{ID: "b", Name: "Coding", IssueCount: 2},
+	}
+
+	tests := []struct {
+		key        string
+		descending bool
+		want       []string
+	}{
+		{key: SortName, want: []string{"c", "b", "a"}},
+		{key: SortID, descending: true, want: []string{"c", "b", "a"}},
+		{key: SortIssueCount, want: []string{"a", "c", "b"}},
+		{key: SortIssueCount, descending: true, want: []string{"c", "b", "a"}},
+	}
+
+	for _, test := range tests {
+		sorted := append([]PipelineSummary(nil), pipelines...)
+		SortPipelines(sorted, test.key, test.descending)
+		var got []string
+		for _, pipeline := range sorted {
+			got = append(got, pipeline.ID)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SortPipelines by %s descending %t: expected %v, got %v", test.key, test.descending, test.want, got)
+		}
+	}
+}
+
+func TestSortBoardIssues(t *testing.T) {
+	board := &zenhub.Board{Pipelines: []zenhub.Pipeline{{
+		ID: "p1",
+		Issues: []zenhub.BoardIssue{
+			{IssueNumber: 3, Estimate: &zenhub.Estimate{Value: 0}},
+			{IssueNumber: 1, Estimate: &zenhub.Estimate{Value: 5}},
+			{IssueNumber: 2},
+		},
+	}}}
+
+	SortBoardIssues(board, SortEstimate, false)
+	var got []int
+	for _, issue := range board.Pipelines[0].Issues {
+		got = append(got, issue.IssueNumber)
+	}
+	if want := []int{2, 3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected unestimated issues first, then by estimate %v, got %v", want, got)
+	}
+}
+
+func TestListPipelinesCommandSort(t *testing.T) {
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"pipelines": [
+			{"id": "p1", "name": "Backlog", "issues": [{"issue_number": 1}]},
+			{"id": "p2", "name": "In Progress", "issues": [{"issue_number": 2}, {"issue_number": 3}]}
+		]}`)
+	})
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "pipeline", "ls", "--sort", "issue_count:desc")
+	})
+	if err != nil {
+		t.Fatalf("failed to list pipelines: %v", err)
+	}
+	if want := "p2\tIn Progress\t2\np1\tBacklog\t1\n"; output != want {
+		t.Errorf("expected pipelines by issue count, descending:\n%s\ngot:\n%s", want, output)
+	}
+
+	requests := len(server.Requests())
+	err = runApp(t, server, "--repository-id", "1", "--workspace-id", "ws1", "pipeline", "ls", "--sort", "estimate")
+	if err == nil || !strings.Contains(err.Error(), "invalid sort value of estimate") {
+		t.Errorf("expected an unsortable field to be an error, got: %v", err)
+	}
+	if len(server.Requests()) != requests {
+		t.Errorf("expected the sort to be checked before calling the API")
+	}
+}
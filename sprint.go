@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/urfave/cli/v2"
+)
+
+// CurrentSprintCommand prints the workspace's currently active sprint and its
+// issues. Sprints are only available through the GraphQL API.
+func CurrentSprintCommand(ctx *cli.Context) error {
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := RequireGraphQL(client, "sprint current"); err != nil {
+		return err
+	}
+
+	var repositoryID uint
+	if explicitWorkspaceID(ctx) == "" {
+		id, err := ResolveRepositoryID(ctx)
+		if err != nil {
+			return fmt.Errorf("workspace-id not set so the repository is needed to infer it: %w", err)
+		}
+		repositoryID = id
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	sprints, err := client.GetSprints(workspaceID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var sprint *zenhub.Sprint
+	for i := range sprints {
+		if now.Before(sprints[i].StartAt) || !now.Before(sprints[i].EndAt) {
+			continue
+		}
+		sprint = &sprints[i]
+		break
+	}
+	if sprint == nil {
+		return fmt.Errorf("no sprint is active in workspace %s", workspaceID)
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(sprint)
+	}
+
+	fmt.Printf("%s (%s to %s)\n", sprint.Name, sprint.StartAt.Format("2006-01-02"), sprint.EndAt.Format("2006-01-02"))
+	for _, issue := range sprint.Issues {
+		estimate := "-"
+		if issue.Estimate != nil {
+			estimate = fmt.Sprint(issue.Estimate.Value)
+		}
+		fmt.Printf("#%d\t%s\t%s\n", issue.Number, estimate, issue.Title)
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// UndoFileName is the name of the file within the zh state directory the
+// last `issue mv` is recorded in.
+const UndoFileName string = "last-move.json"
+
+// UndoRecord is the record of the last `issue mv`, so `issue undo` can put
+// the issues back.
+type UndoRecord struct {
+	WorkspaceID  string     `json:"workspace_id"`
+	RepositoryID uint       `json:"repository_id"`
+	Moves        []UndoMove `json:"moves"`
+}
+
+// UndoMove is a single issue moved by the last `issue mv`.
+type UndoMove struct {
+	IssueNumber    int    `json:"issue_number"`
+	FromPipelineID string `json:"from_pipeline_id"`
+	ToPipelineID   string `json:"to_pipeline_id"`
+
+	// FromPosition is the issue's index in its previous pipeline. It is
+	// empty if `issue mv` didn't fetch the board, in which case the issue
+	// is put back at the bottom of the pipeline.
+	FromPosition string `json:"from_position,omitempty"`
+}
+
+// UndoPath returns the path of the undo record,
+// `$XDG_STATE_HOME/zh/last-move.json`.
+func UndoPath() (string, error) {
+	return StatePath(UndoFileName)
+}
+
+// StatePath returns the path of the given file within the zh state
+// directory, `$XDG_STATE_HOME/zh`, falling back to `~/.local/state` if
+// `XDG_STATE_HOME` isn't set.
+func StatePath(fileName string) (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find state directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "zh", fileName), nil
+}
+
+// LoadUndoRecord reads the undo record, returning nil if no move has been
+// recorded.
+func LoadUndoRecord() (*UndoRecord, error) {
+	path, err := UndoPath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read undo record %s: %w", path, err)
+	}
+
+	var record UndoRecord
+	if err := json.Unmarshal(contents, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode undo record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// SaveUndoRecord writes the undo record, replacing any previous one. A
+// record with no moves removes the previous one instead.
+func SaveUndoRecord(record UndoRecord) error {
+	path, err := UndoPath()
+	if err != nil {
+		return err
+	}
+
+	if len(record.Moves) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove undo record %s: %w", path, err)
+		}
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert undo record to JSON: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write undo record %s: %w", path, err)
+	}
+	return nil
+}
+
+// previousLocation returns where the given issue is before it is moved to
+// the given pipeline, for the undo record. The pipeline comes from the
+// issue's data and the position from the board, if it was fetched.
+func (m *IssueMover) previousLocation(issueID int, pipelineID string) (UndoMove, error) {
+	issue, err := m.client.GetIssueData(m.repositoryID, issueID)
+	if err != nil {
+		return UndoMove{}, err
+	}
+
+	move := UndoMove{
+		IssueNumber:    issueID,
+		FromPipelineID: issue.Pipeline.PipelineID,
+		ToPipelineID:   pipelineID,
+	}
+	if m.index != nil {
+		m.mu.Lock()
+		position, err := m.index.IssuePosition(issueID)
+		m.mu.Unlock()
+		if err == nil && position.PipelineID == move.FromPipelineID {
+			move.FromPosition = strconv.Itoa(position.Index)
+		}
+	}
+	return move, nil
+}
+
+// UndoMoveCommand moves the issues moved by the last `issue mv` back to the
+// pipelines, and where known the positions, they were in before. Issues
+// that fail to move back are kept in the undo record so undo can be run
+// again.
+func UndoMoveCommand(ctx *cli.Context) error {
+	record, err := LoadUndoRecord()
+	if err != nil {
+		return err
+	}
+	if record == nil || len(record.Moves) == 0 {
+		if !IsStructuredOutput(ctx) && !IsQuiet(ctx) {
+			fmt.Println("Nothing to undo, no move has been recorded")
+		}
+		return nil
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	total := len(record.Moves)
+
+	// Issues are moved back in the reverse order they were moved in so
+	// recorded positions are restored relative to each other.
+	var remaining []UndoMove
+	for i := len(record.Moves) - 1; i >= 0; i-- {
+		move := record.Moves[i]
+		if ctx.Err() != nil {
+			remaining = append([]UndoMove{move}, remaining...)
+			continue
+		}
+
+		position := move.FromPosition
+		if position == "" {
+			position = "bottom"
+		}
+		request := zenhub.MoveIssueRequest{PipelineID: move.FromPipelineID, Position: zenhub.MovePosition(position)}
+		if err := client.MoveIssue(record.WorkspaceID, record.RepositoryID, move.IssueNumber, request); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"issue_id": move.IssueNumber,
+				"error":    err,
+			}).Error("Failed to move issue back")
+			remaining = append([]UndoMove{move}, remaining...)
+			continue
+		}
+
+		result := MoveResult{
+			IssueID:    move.IssueNumber,
+			PipelineID: move.FromPipelineID,
+			Status:     MoveStatusMoved,
+			Resolved: ResolvedIDs{
+				WorkspaceID:  record.WorkspaceID,
+				RepositoryID: record.RepositoryID,
+				PipelineID:   move.FromPipelineID,
+			},
+		}
+		if ctx.Bool("dry-run") {
+			result.Status = MoveStatusPlanned
+		}
+		switch {
+		case IsStructuredOutput(ctx):
+			if err := PrintStructured(result); err != nil {
+				return err
+			}
+		case IsQuiet(ctx):
+		case result.Status == MoveStatusPlanned:
+			fmt.Printf("Would move issue %d back to pipeline %s\n", move.IssueNumber, move.FromPipelineID)
+		default:
+			fmt.Printf("Moved issue %d back to pipeline %s\n", move.IssueNumber, move.FromPipelineID)
+		}
+	}
+
+	if ctx.Bool("dry-run") {
+		return nil
+	}
+
+	record.Moves = remaining
+	if err := SaveUndoRecord(*record); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("interrupted before moving %d issues back: %w", len(remaining), ctx.Err())
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("failed to move %d of %d issues back, run undo again to retry", len(remaining), total)
+	}
+	return nil
+}
+
+// saveUndoRecord records the moves made, in the order the issues were
+// given in, replacing the record of any earlier `issue mv`. Nothing is
+// recorded if no issues were moved.
+func (m *IssueMover) saveUndoRecord(issueIDs []int) error {
+	if len(m.undo) == 0 {
+		return nil
+	}
+
+	order := make(map[int]int, len(issueIDs))
+	for i, issueID := range issueIDs {
+		order[issueID] = i
+	}
+	moves := append([]UndoMove(nil), m.undo...)
+	sort.SliceStable(moves, func(i, j int) bool {
+		return order[moves[i].IssueNumber] < order[moves[j].IssueNumber]
+	})
+
+	return SaveUndoRecord(UndoRecord{
+		WorkspaceID:  m.workspaceID,
+		RepositoryID: m.repositoryID,
+		Moves:        moves,
+	})
+}
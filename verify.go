@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/urfave/cli/v2"
+)
+
+// ExpectedMove is the pipeline an issue is expected to be in after a move.
+type ExpectedMove struct {
+	IssueNumber int
+	PipelineID  string
+}
+
+// IssueVerification is whether an issue landed in its intended pipeline.
+type IssueVerification struct {
+	IssueNumber        int    `json:"issue_number"`
+	ExpectedPipelineID string `json:"expected_pipeline_id"`
+	ActualPipelineID   string `json:"actual_pipeline_id"`
+	Landed             bool   `json:"landed"`
+}
+
+// VerificationReport is the result of checking a set of moves against the
+// board.
+type VerificationReport struct {
+	Issues   []IssueVerification `json:"issues"`
+	Verified bool                `json:"verified"`
+}
+
+// VerifyMoves checks each issue is in its expected pipeline on the (freshly
+// fetched) board.
+func VerifyMoves(index *zenhub.BoardIndex, moves []ExpectedMove) VerificationReport {
+	report := VerificationReport{Verified: true}
+	for _, move := range moves {
+		verification := IssueVerification{
+			IssueNumber:        move.IssueNumber,
+			ExpectedPipelineID: move.PipelineID,
+		}
+		if pipeline, _ := index.Issue(move.IssueNumber); pipeline != nil {
+			verification.ActualPipelineID = pipeline.ID
+		}
+		verification.Landed = verification.ActualPipelineID == move.PipelineID
+		if !verification.Landed {
+			report.Verified = false
+		}
+		report.Issues = append(report.Issues, verification)
+	}
+	return report
+}
+
+// PrintVerificationReport prints the report as text or structured output,
+// returning an error if any issue didn't land in its intended pipeline.
+func PrintVerificationReport(ctx *cli.Context, report VerificationReport) error {
+	if IsStructuredOutput(ctx) {
+		if err := PrintStructured(report); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range report.Issues {
+			if issue.Landed {
+				fmt.Printf("Verified issue %d is in pipeline %s\n", issue.IssueNumber, issue.ActualPipelineID)
+			} else if issue.ActualPipelineID == "" {
+				fmt.Printf("Issue %d is not on the board, expected it in pipeline %s\n", issue.IssueNumber, issue.ExpectedPipelineID)
+			} else {
+				fmt.Printf("Issue %d is in pipeline %s, expected it in pipeline %s\n",
+					issue.IssueNumber, issue.ActualPipelineID, issue.ExpectedPipelineID)
+			}
+		}
+	}
+
+	return report.Err()
+}
+
+// Err returns an error describing the issues that didn't land in their
+// intended pipeline, or nil if they all did.
+func (r VerificationReport) Err() error {
+	if r.Verified {
+		return nil
+	}
+	failed := 0
+	for _, issue := range r.Issues {
+		if !issue.Landed {
+			failed++
+		}
+	}
+	return fmt.Errorf("%d of %d issues did not land in their intended pipeline", failed, len(r.Issues))
+}
@@ -1,7 +1,33 @@
 package version
 
+import "fmt"
+
 // VERSION indicates which version of the binary is running.
 var VERSION string
 
 // GITCOMMIT indicates which git hash the binary was built off of
 var GITCOMMIT string
+
+// BUILDDATE indicates when the binary was built
+var BUILDDATE string
+
+// Unset is reported for any of the above that wasn't set at build time,
+// e.g. when built with a plain `go build`.
+const Unset = "dev"
+
+// Version returns VERSION, or `Unset` if it wasn't set.
+func Version() string {
+	return orUnset(VERSION)
+}
+
+// String describes the build: its version, git commit and build date.
+func String() string {
+	return fmt.Sprintf("%s (commit %s, built %s)", orUnset(VERSION), orUnset(GITCOMMIT), orUnset(BUILDDATE))
+}
+
+func orUnset(value string) string {
+	if value == "" {
+		return Unset
+	}
+	return value
+}
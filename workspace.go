@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nick96/zh/pkg/zenhub"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// ListWorkspacePipelinesCommand lists the pipelines in the workspace, like
+// `ListPipelinesCommand`.
+//
+// With the `json-map` flag the pipelines are printed as a JSON object mapping
+// pipeline names to IDs, for other tools to import.
+func ListWorkspacePipelinesCommand(ctx *cli.Context) error {
+	if !ctx.Bool("json-map") {
+		return ListPipelinesCommand(ctx)
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaceID, err := ResolveWorkspaceID(ctx, client, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	board, err := GetBoard(ctx, client, workspaceID, repositoryID)
+	if err != nil {
+		return err
+	}
+
+	pipelines := make(map[string]string, len(board.Pipelines))
+	for _, pipeline := range board.Pipelines {
+		pipelines[pipeline.Name] = pipeline.ID
+	}
+	return PrintStructured(pipelines)
+}
+
+// ListWorkspacesCommand lists the name and ID of each workspace the
+// repository belongs to, to find the workspace ID to use. They are listed in
+// the order the API returns them unless sorted by the `sort` flag.
+func ListWorkspacesCommand(ctx *cli.Context) error {
+	var key string
+	var descending bool
+	if value := ctx.String("sort"); value != "" {
+		var err error
+		if key, descending, err = ParseSort(value, WorkspaceSortKeys...); err != nil {
+			return err
+		}
+	}
+
+	repositoryID, err := ResolveRepositoryID(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	workspaces, err := client.GetWorkspaces(repositoryID)
+	if err != nil {
+		return err
+	}
+
+	workspaceIDCache.Lock()
+	cacheWorkspaceIDs(repositoryID, unambiguousWorkspaces(workspaces))
+	workspaceIDCache.Unlock()
+
+	if key != "" {
+		SortWorkspaces(workspaces, key, descending)
+	}
+
+	if IsStructuredOutput(ctx) {
+		return PrintStructured(workspaces)
+	}
+
+	if len(workspaces) == 0 {
+		fmt.Printf("Repository %d belongs to no workspaces\n", repositoryID)
+		return nil
+	}
+	for _, workspace := range workspaces {
+		fmt.Printf("%s\t%s\n", workspace.ID, workspace.Name)
+	}
+
+	return nil
+}
+
+// WorkspaceCacheFileName is the name of the file within the zh state
+// directory the IDs of workspaces resolved by name are cached in.
+const WorkspaceCacheFileName string = "workspaces.json"
+
+// workspaceIDCache holds the IDs of workspaces already resolved by name,
+// keyed by repository ID and then lower cased name. It is loaded from the
+// cache file the first time it is needed, so each name is only looked up
+// once across invocations.
+var workspaceIDCache = struct {
+	sync.Mutex
+	loaded bool
+	ids    map[string]map[string]string
+}{}
+
+// ensureWorkspaceCacheLoaded loads the cache file the first time the cache
+// is used. The cache must be locked.
+func ensureWorkspaceCacheLoaded() {
+	if workspaceIDCache.loaded {
+		return
+	}
+	workspaceIDCache.loaded = true
+	ids, err := loadWorkspaceCache()
+	if err != nil {
+		logrus.WithField("error", err).Warn("Ignoring unreadable workspace cache")
+	}
+	workspaceIDCache.ids = ids
+}
+
+// cachedWorkspaceID returns the cached ID of the named workspace, or empty
+// if it isn't cached. The cache must be locked.
+func cachedWorkspaceID(repositoryID uint, name string) string {
+	ensureWorkspaceCacheLoaded()
+	return workspaceIDCache.ids[strconv.FormatUint(uint64(repositoryID), 10)][strings.ToLower(name)]
+}
+
+// cacheWorkspaceIDs replaces the cached workspaces of the repository with
+// the given ones and saves the cache. The cache must be locked. Failing to
+// save it is only logged, since the workspaces can be looked up again.
+func cacheWorkspaceIDs(repositoryID uint, workspaces []zenhub.Workspace) {
+	ensureWorkspaceCacheLoaded()
+	ids := make(map[string]string, len(workspaces))
+	for _, workspace := range workspaces {
+		ids[strings.ToLower(workspace.Name)] = workspace.ID
+	}
+	workspaceIDCache.ids[strconv.FormatUint(uint64(repositoryID), 10)] = ids
+
+	if err := saveWorkspaceCache(workspaceIDCache.ids); err != nil {
+		logrus.WithField("error", err).Warn("Failed to save workspace cache")
+	}
+}
+
+// InvalidateWorkspaceID removes the workspaces cached with the given ID, so
+// their names are looked up again next time. It is called when ZenHub doesn't
+// find a workspace by a possibly cached ID, as the workspace may have been
+// deleted and recreated under the same name with a new ID.
+func InvalidateWorkspaceID(workspaceID string) {
+	workspaceIDCache.Lock()
+	defer workspaceIDCache.Unlock()
+	ensureWorkspaceCacheLoaded()
+
+	removed := false
+	for repository, ids := range workspaceIDCache.ids {
+		for name, id := range ids {
+			if id != workspaceID {
+				continue
+			}
+			logrus.WithFields(logrus.Fields{
+				"repository_id":  repository,
+				"workspace_id":   workspaceID,
+				"workspace_name": name,
+			}).Debug("Invalidated cached workspace ID")
+			delete(ids, name)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	if err := saveWorkspaceCache(workspaceIDCache.ids); err != nil {
+		logrus.WithField("error", err).Warn("Failed to save workspace cache")
+	}
+}
+
+// loadWorkspaceCache reads the workspace cache file. A missing file is an
+// empty cache.
+func loadWorkspaceCache() (map[string]map[string]string, error) {
+	ids := map[string]map[string]string{}
+	path, err := StatePath(WorkspaceCacheFileName)
+	if err != nil {
+		return ids, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return ids, fmt.Errorf("failed to read workspace cache %s: %w", path, err)
+	}
+	if err := json.Unmarshal(contents, &ids); err != nil {
+		return map[string]map[string]string{}, fmt.Errorf("failed to decode workspace cache %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// saveWorkspaceCache writes the workspace cache file, replacing any
+// previous one.
+func saveWorkspaceCache(ids map[string]map[string]string) error {
+	path, err := StatePath(WorkspaceCacheFileName)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert workspace cache to JSON: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := ioutil.WriteFile(path, contents, 0600); err != nil {
+		return fmt.Errorf("failed to write workspace cache %s: %w", path, err)
+	}
+	return nil
+}
+
+// ResolveWorkspaceID returns the ID of the target workspace. An explicit
+// `workspace-id` flag takes precedence, then the `workspace` flag's name is
+// looked up among the repository's workspaces, then the default
+// `workspace-id` from the environment or config file is used. If none are
+// set, the workspace is inferred from the repository as long as the
+// repository belongs to exactly one workspace.
+func ResolveWorkspaceID(ctx *cli.Context, client *zenhub.Client, repositoryID uint) (string, error) {
+	if workspaceID := explicitWorkspaceID(ctx); workspaceID != "" {
+		return workspaceID, nil
+	}
+	if name := strings.TrimSpace(ctx.String("workspace")); name != "" {
+		return ResolveWorkspaceIDByName(client, repositoryID, name)
+	}
+
+	workspaces, err := client.GetWorkspaces(repositoryID)
+	if err != nil {
+		return "", fmt.Errorf("workspace-id not set and failed to infer it: %w", err)
+	}
+
+	switch len(workspaces) {
+	case 0:
+		return "", WorkspaceIDSetting.MissingError(fmt.Sprintf("repository %d belongs to no workspaces", repositoryID))
+	case 1:
+		logrus.WithFields(logrus.Fields{
+			"workspace_id":   workspaces[0].ID,
+			"workspace_name": workspaces[0].Name,
+		}).Debug("Inferred workspace from repository")
+		return workspaces[0].ID, nil
+	default:
+		return "", WorkspaceIDSetting.MissingError(fmt.Sprintf("repository %d belongs to multiple workspaces: %s",
+			repositoryID, formatWorkspaces(workspaces)))
+	}
+}
+
+// explicitWorkspaceID returns the workspace ID that applies without looking
+// anything up: the `workspace-id` flag if it was given, otherwise its default
+// unless the `workspace` flag names a workspace instead. It is empty if the
+// workspace has to be resolved from the repository.
+func explicitWorkspaceID(ctx *cli.Context) string {
+	if ctx.IsSet("workspace-id") {
+		return ctx.String("workspace-id")
+	}
+	if strings.TrimSpace(ctx.String("workspace")) != "" {
+		return ""
+	}
+	if workspaceID := ctx.String("workspace-id"); workspaceID != "" {
+		return workspaceID
+	}
+	return fileConfig.WorkspaceID
+}
+
+// ResolveWorkspaceIDByName returns the ID of the workspace with the given
+// name among those the repository belongs to. Names are matched ignoring
+// case. Resolved names are cached in the state directory, along with the
+// rest of the repository's workspaces; `workspace ls` refreshes the cache.
+func ResolveWorkspaceIDByName(client *zenhub.Client, repositoryID uint, name string) (string, error) {
+	workspaceIDCache.Lock()
+	defer workspaceIDCache.Unlock()
+	if workspaceID := cachedWorkspaceID(repositoryID, name); workspaceID != "" {
+		logrus.WithFields(logrus.Fields{
+			"workspace_id":   workspaceID,
+			"workspace_name": name,
+		}).Debug("Resolved workspace by name from the cache")
+		return workspaceID, nil
+	}
+
+	workspaces, err := client.GetWorkspaces(repositoryID)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace %s: %w", name, err)
+	}
+
+	var matches []zenhub.Workspace
+	for _, workspace := range workspaces {
+		if strings.EqualFold(workspace.Name, name) {
+			matches = append(matches, workspace)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no workspace named %s found for repository %d", name, repositoryID)
+	case 1:
+		logrus.WithFields(logrus.Fields{
+			"workspace_id":   matches[0].ID,
+			"workspace_name": matches[0].Name,
+		}).Debug("Resolved workspace by name")
+		cacheWorkspaceIDs(repositoryID, unambiguousWorkspaces(workspaces))
+		return matches[0].ID, nil
+	default:
+		return "", fmt.Errorf("workspace name %s is ambiguous, set workspace-id to one of: %s", name, formatWorkspaces(matches))
+	}
+}
+
+// unambiguousWorkspaces returns the workspaces whose name, ignoring case,
+// no other workspace has, so only names that resolve to one workspace are
+// cached.
+func unambiguousWorkspaces(workspaces []zenhub.Workspace) []zenhub.Workspace {
+	counts := make(map[string]int, len(workspaces))
+	for _, workspace := range workspaces {
+		counts[strings.ToLower(workspace.Name)]++
+	}
+	unambiguous := make([]zenhub.Workspace, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		if counts[strings.ToLower(workspace.Name)] == 1 {
+			unambiguous = append(unambiguous, workspace)
+		}
+	}
+	return unambiguous
+}
+
+// formatWorkspaces formats workspaces as a comma separated list of their
+// IDs and names.
+func formatWorkspaces(workspaces []zenhub.Workspace) string {
+	formatted := make([]string, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		formatted = append(formatted, fmt.Sprintf("%s (%s)", workspace.ID, workspace.Name))
+	}
+	return strings.Join(formatted, ", ")
+}
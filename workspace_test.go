@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestInvalidateWorkspaceID(t *testing.T) {
+	var boardFound bool
+	server := withZenHubServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/workspaces") {
+			fmt.Fprint(w, `[{"id": "ws1", "name": "Team"}]`)
+			return
+		}
+		if !boardFound {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"pipelines": []}`)
+	})
+	workspaceIDCache.Lock()
+	workspaceIDCache.loaded = false
+	workspaceIDCache.ids = nil
+	workspaceIDCache.Unlock()
+
+	workspaceRequests := func() int {
+		count := 0
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request.Path, "/workspaces") {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The workspace isn't found by its cached ID, which is forgotten.
+	args := []string{"--api", "rest", "--repository-id", "1", "--workspace", "Team", "board", "--no-titles"}
+	if err := runApp(t, server, args...); err == nil || !strings.Contains(err.Error(), "endpoint not found") {
+		t.Fatalf("expected the board to not be found, got: %v", err)
+	}
+	workspaceIDCache.Lock()
+	id := cachedWorkspaceID(1, "Team")
+	workspaceIDCache.Unlock()
+	if id != "" {
+		t.Errorf("expected workspace ID %s to be invalidated", id)
+	}
+
+	boardFound = true
+	if err := runApp(t, server, args...); err != nil {
+		t.Fatalf("failed to show board: %v", err)
+	}
+	if err := runApp(t, server, args...); err != nil {
+		t.Fatalf("failed to show board: %v", err)
+	}
+	if count := workspaceRequests(); count != 2 {
+		t.Errorf("expected the workspace to be looked up again once after the 404, got %d lookups", count)
+	}
+}